@@ -0,0 +1,271 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Роли пользователей
+const (
+	RoleUser  = "user"
+	RoleChef  = "chef"
+	RoleAdmin = "admin"
+)
+
+const sessionTTL = 24 * time.Hour
+
+type contextKey string
+
+const ctxUserKey contextKey = "auth_user"
+
+// AuthUser описывает вызывающего пользователя, извлечённого из сессии
+type AuthUser struct {
+	ID   int    `json:"id"`
+	Role string `json:"role"`
+}
+
+type registerRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Email    string `json:"email"`
+}
+
+type loginRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// loginAttempts хранит тайминги последних неудачных попыток входа по логину,
+// чтобы грубая сила упиралась в rate limit, а не в базу данных
+var loginAttemptsMu sync.Mutex
+var loginAttempts = map[string][]time.Time{}
+
+const maxLoginAttempts = 5
+const loginAttemptWindow = 10 * time.Minute
+
+func createSessionsTable() {
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS sessions (
+		token TEXT PRIMARY KEY,
+		user_id INTEGER NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		expires_at DATETIME NOT NULL,
+		FOREIGN KEY (user_id) REFERENCES users (id)
+	)`)
+	if err != nil {
+		log.Printf("Error creating sessions table: %v", err)
+	}
+}
+
+func addAuthColumns() {
+	// password_hash и role могут отсутствовать в старой базе - добавляем их, если нужно
+	alterations := []string{
+		`ALTER TABLE users ADD COLUMN password_hash TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE users ADD COLUMN role TEXT NOT NULL DEFAULT 'user'`,
+		// chef_id связывает аккаунт повара со строкой в chefs - users.id и
+		// chefs.id это разные пространства идентификаторов (chefs существовала
+		// до системы аккаунтов и заполняется отдельно), см. ensureChefForUser
+		`ALTER TABLE users ADD COLUMN chef_id INTEGER REFERENCES chefs (id)`,
+	}
+	for _, stmt := range alterations {
+		if _, err := db.Exec(stmt); err != nil {
+			// колонка уже существует - это нормально для уже созданной базы
+			if !strings.Contains(err.Error(), "duplicate column name") {
+				log.Printf("Error altering users table: %v", err)
+			}
+		}
+	}
+}
+
+func generateToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+func isRateLimited(username string) bool {
+	loginAttemptsMu.Lock()
+	defer loginAttemptsMu.Unlock()
+
+	cutoff := time.Now().Add(-loginAttemptWindow)
+	attempts := loginAttempts[username]
+
+	var recent []time.Time
+	for _, t := range attempts {
+		if t.After(cutoff) {
+			recent = append(recent, t)
+		}
+	}
+	loginAttempts[username] = recent
+
+	return len(recent) >= maxLoginAttempts
+}
+
+func recordFailedLogin(username string) {
+	loginAttemptsMu.Lock()
+	defer loginAttemptsMu.Unlock()
+	loginAttempts[username] = append(loginAttempts[username], time.Now())
+}
+
+func clearLoginAttempts(username string) {
+	loginAttemptsMu.Lock()
+	defer loginAttemptsMu.Unlock()
+	delete(loginAttempts, username)
+}
+
+func register(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req registerRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	if req.Username == "" || req.Password == "" || req.Email == "" {
+		http.Error(w, "username, password and email are required", http.StatusBadRequest)
+		return
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		log.Printf("Error hashing password: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	result, err := db.Exec("INSERT INTO users (username, email, preferences, password_hash, role) VALUES (?, ?, ?, ?, ?)",
+		req.Username, req.Email, "", string(hash), RoleUser)
+	if err != nil {
+		log.Printf("Error creating user: %v", err)
+		http.Error(w, "Username or email already taken", http.StatusConflict)
+		return
+	}
+
+	id, _ := result.LastInsertId()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"id":       id,
+		"username": req.Username,
+		"role":     RoleUser,
+	})
+}
+
+func login(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req loginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	if isRateLimited(req.Username) {
+		http.Error(w, "Too many login attempts, try again later", http.StatusTooManyRequests)
+		return
+	}
+
+	var userID int
+	var passwordHash, role string
+	err := db.QueryRow("SELECT id, password_hash, role FROM users WHERE username = ?", req.Username).
+		Scan(&userID, &passwordHash, &role)
+	if err != nil {
+		recordFailedLogin(req.Username)
+		http.Error(w, "Invalid username or password", http.StatusUnauthorized)
+		return
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(passwordHash), []byte(req.Password)); err != nil {
+		recordFailedLogin(req.Username)
+		http.Error(w, "Invalid username or password", http.StatusUnauthorized)
+		return
+	}
+
+	clearLoginAttempts(req.Username)
+
+	token, err := generateToken()
+	if err != nil {
+		log.Printf("Error generating session token: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	expiresAt := time.Now().Add(sessionTTL)
+	_, err = db.Exec("INSERT INTO sessions (token, user_id, expires_at) VALUES (?, ?, ?)", token, userID, expiresAt)
+	if err != nil {
+		log.Printf("Error creating session: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"token":   token,
+		"user_id": userID,
+		"role":    role,
+	})
+}
+
+func userFromToken(token string) (*AuthUser, error) {
+	var userID int
+	var role string
+	var expiresAt time.Time
+
+	err := db.QueryRow("SELECT user_id, role, expires_at FROM sessions s JOIN users u ON s.user_id = u.id WHERE token = ?", token).
+		Scan(&userID, &role, &expiresAt)
+	if err != nil {
+		return nil, errors.New("invalid session")
+	}
+
+	if time.Now().After(expiresAt) {
+		db.Exec("DELETE FROM sessions WHERE token = ?", token)
+		return nil, errors.New("session expired")
+	}
+
+	return &AuthUser{ID: userID, Role: role}, nil
+}
+
+// authMiddleware извлекает токен из заголовка Authorization: Bearer <token>
+// и кладёт вызывающего пользователя в контекст запроса
+func authMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		header := r.Header.Get("Authorization")
+		if !strings.HasPrefix(header, "Bearer ") {
+			http.Error(w, "Missing or invalid Authorization header", http.StatusUnauthorized)
+			return
+		}
+
+		token := strings.TrimPrefix(header, "Bearer ")
+		user, err := userFromToken(token)
+		if err != nil {
+			http.Error(w, "Invalid or expired session", http.StatusUnauthorized)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), ctxUserKey, user)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	}
+}
+
+func userFromContext(r *http.Request) (*AuthUser, bool) {
+	user, ok := r.Context().Value(ctxUserKey).(*AuthUser)
+	return user, ok
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func doJSONRequest(handler http.HandlerFunc, method, path string, body interface{}) *httptest.ResponseRecorder {
+	var reader *bytes.Reader
+	if body != nil {
+		raw, _ := json.Marshal(body)
+		reader = bytes.NewReader(raw)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+
+	r := httptest.NewRequest(method, path, reader)
+	w := httptest.NewRecorder()
+	handler(w, r)
+	return w
+}
+
+func TestRegisterAndLogin(t *testing.T) {
+	setupTestDB(t)
+
+	regResp := doJSONRequest(register, "POST", "/api/v1/register", registerRequest{
+		Username: "chef_anna",
+		Password: "correct-horse",
+		Email:    "anna@example.com",
+	})
+	if regResp.Code != http.StatusOK {
+		t.Fatalf("register: expected 200, got %d: %s", regResp.Code, regResp.Body.String())
+	}
+
+	var storedHash string
+	if err := db.QueryRow("SELECT password_hash FROM users WHERE username = ?", "chef_anna").Scan(&storedHash); err != nil {
+		t.Fatalf("Error reading stored password hash: %v", err)
+	}
+	if storedHash == "correct-horse" {
+		t.Fatal("password was stored in plain text instead of bcrypt hash")
+	}
+
+	loginResp := doJSONRequest(login, "POST", "/api/v1/login", loginRequest{
+		Username: "chef_anna",
+		Password: "correct-horse",
+	})
+	if loginResp.Code != http.StatusOK {
+		t.Fatalf("login: expected 200, got %d: %s", loginResp.Code, loginResp.Body.String())
+	}
+
+	var loginBody map[string]interface{}
+	if err := json.Unmarshal(loginResp.Body.Bytes(), &loginBody); err != nil {
+		t.Fatalf("Error decoding login response: %v", err)
+	}
+	if loginBody["token"] == "" || loginBody["token"] == nil {
+		t.Fatal("login response did not include a session token")
+	}
+}
+
+func TestLoginWrongPassword(t *testing.T) {
+	setupTestDB(t)
+
+	doJSONRequest(register, "POST", "/api/v1/register", registerRequest{
+		Username: "chef_boris",
+		Password: "correct-horse",
+		Email:    "boris@example.com",
+	})
+
+	resp := doJSONRequest(login, "POST", "/api/v1/login", loginRequest{
+		Username: "chef_boris",
+		Password: "wrong-password",
+	})
+	if resp.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for wrong password, got %d: %s", resp.Code, resp.Body.String())
+	}
+}
+
+func TestLoginRateLimiting(t *testing.T) {
+	setupTestDB(t)
+	clearLoginAttempts("chef_clara")
+
+	doJSONRequest(register, "POST", "/api/v1/register", registerRequest{
+		Username: "chef_clara",
+		Password: "correct-horse",
+		Email:    "clara@example.com",
+	})
+
+	for i := 0; i < maxLoginAttempts; i++ {
+		resp := doJSONRequest(login, "POST", "/api/v1/login", loginRequest{
+			Username: "chef_clara",
+			Password: "wrong-password",
+		})
+		if resp.Code != http.StatusUnauthorized {
+			t.Fatalf("attempt %d: expected 401, got %d", i+1, resp.Code)
+		}
+	}
+
+	resp := doJSONRequest(login, "POST", "/api/v1/login", loginRequest{
+		Username: "chef_clara",
+		Password: "wrong-password",
+	})
+	if resp.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429 after %d failed attempts, got %d: %s", maxLoginAttempts, resp.Code, resp.Body.String())
+	}
+
+	// Правильный пароль тоже должен упираться в rate limit, пока окно не истечёт
+	resp = doJSONRequest(login, "POST", "/api/v1/login", loginRequest{
+		Username: "chef_clara",
+		Password: "correct-horse",
+	})
+	if resp.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429 to also block correct password during lockout, got %d", resp.Code)
+	}
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// CookingMessage - сообщение живой кулинарной сессии. Type определяет, как
+// фронтенд должен его отрисовать: step, timer, chat, question, done
+type CookingMessage struct {
+	Type          string `json:"type"`
+	MasterClassID int    `json:"master_class_id"`
+	UserID        int    `json:"user_id,omitempty"`
+	Step          string `json:"step,omitempty"`
+	Seconds       int    `json:"seconds,omitempty"`
+	Text          string `json:"text,omitempty"`
+}
+
+type cookingClient struct {
+	conn   *websocket.Conn
+	userID int
+	send   chan CookingMessage
+}
+
+// cookingRoom - комната одного мастер-класса: подключённые клиенты и
+// сохранённая стенограмма сессии для user_history
+type cookingRoom struct {
+	mu         sync.Mutex
+	clients    map[*cookingClient]bool
+	transcript []CookingMessage
+}
+
+// SessionHub управляет комнатами живых кулинарных сессий по мастер-классам
+type SessionHub struct {
+	mu    sync.Mutex
+	rooms map[int]*cookingRoom
+}
+
+func newSessionHub() *SessionHub {
+	return &SessionHub{rooms: map[int]*cookingRoom{}}
+}
+
+var globalSessionHub = newSessionHub()
+
+func (h *SessionHub) roomFor(masterClassID int) *cookingRoom {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	room, ok := h.rooms[masterClassID]
+	if !ok {
+		room = &cookingRoom{clients: map[*cookingClient]bool{}}
+		h.rooms[masterClassID] = room
+	}
+	return room
+}
+
+// TotalPresence возвращает общее число пользователей, готовящих прямо сейчас,
+// по всем мастер-классам - используется /api/stats
+func (h *SessionHub) TotalPresence() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	total := 0
+	for _, room := range h.rooms {
+		room.mu.Lock()
+		total += len(room.clients)
+		room.mu.Unlock()
+	}
+	return total
+}
+
+func (room *cookingRoom) broadcast(msg CookingMessage) {
+	room.mu.Lock()
+	defer room.mu.Unlock()
+
+	room.transcript = append(room.transcript, msg)
+
+	for client := range room.clients {
+		select {
+		case client.send <- msg:
+		default:
+			log.Printf("Dropping cooking message for slow client (user %d)", client.userID)
+		}
+	}
+}
+
+func (room *cookingRoom) addClient(c *cookingClient) {
+	room.mu.Lock()
+	room.clients[c] = true
+	room.mu.Unlock()
+}
+
+func (room *cookingRoom) removeClient(c *cookingClient) {
+	room.mu.Lock()
+	delete(room.clients, c)
+	room.mu.Unlock()
+	close(c.send)
+}
+
+// persistSessionTranscript сохраняет стенограмму сессии, привязанную к user_history пользователя
+func persistSessionTranscript(userID, masterClassID int, transcript []CookingMessage) {
+	raw, err := json.Marshal(transcript)
+	if err != nil {
+		log.Printf("Error marshaling session transcript: %v", err)
+		return
+	}
+
+	_, err = db.Exec(`UPDATE user_history SET transcript = ? WHERE user_id = ? AND master_class_id = ?`,
+		string(raw), userID, masterClassID)
+	if err != nil {
+		log.Printf("Error persisting session transcript: %v", err)
+	}
+}
+
+func addTranscriptColumn() {
+	_, err := db.Exec(`ALTER TABLE user_history ADD COLUMN transcript TEXT`)
+	if err != nil && !strings.Contains(err.Error(), "duplicate column name") {
+		log.Printf("Error adding transcript column: %v", err)
+	}
+}
+
+var cookingUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	CheckOrigin:     func(r *http.Request) bool { return true },
+}
+
+const cookingWriteWait = 10 * time.Second
+
+// handleCookingSession открывает /api/cook/ws для зачисленного пользователя
+// или ведущего повара: сервер транслирует шаги и таймеры, принимает "step
+// done"/"question" от клиента и пересылает чат между инструктором и всеми
+// участниками комнаты
+func handleCookingSession(w http.ResponseWriter, r *http.Request) {
+	user, ok := userFromContext(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	masterClassID, err := strconv.Atoi(r.URL.Query().Get("master_class_id"))
+	if err != nil {
+		http.Error(w, "master_class_id is required", http.StatusBadRequest)
+		return
+	}
+
+	var enrolled int
+	db.QueryRow("SELECT COUNT(*) FROM user_history WHERE user_id = ? AND master_class_id = ?", user.ID, masterClassID).Scan(&enrolled)
+
+	var isInstructor int
+	if user.Role == RoleChef || user.Role == RoleAdmin {
+		db.QueryRow(`
+			SELECT COUNT(*) FROM master_classes mc
+			JOIN users u ON u.chef_id = mc.chef_id
+			WHERE mc.id = ? AND u.id = ?
+		`, masterClassID, user.ID).Scan(&isInstructor)
+	}
+
+	if enrolled == 0 && isInstructor == 0 {
+		http.Error(w, "Not enrolled in this master class", http.StatusForbidden)
+		return
+	}
+
+	conn, err := cookingUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Printf("Error upgrading to websocket: %v", err)
+		return
+	}
+
+	client := &cookingClient{conn: conn, userID: user.ID, send: make(chan CookingMessage, eventBufferSize)}
+	room := globalSessionHub.roomFor(masterClassID)
+	room.addClient(client)
+
+	go client.writeLoop()
+	client.readLoop(room, masterClassID)
+
+	room.removeClient(client)
+	conn.Close()
+
+	room.mu.Lock()
+	transcript := append([]CookingMessage{}, room.transcript...)
+	room.mu.Unlock()
+	persistSessionTranscript(user.ID, masterClassID, transcript)
+}
+
+func (c *cookingClient) writeLoop() {
+	for msg := range c.send {
+		c.conn.SetWriteDeadline(time.Now().Add(cookingWriteWait))
+		if err := c.conn.WriteJSON(msg); err != nil {
+			return
+		}
+	}
+}
+
+func (c *cookingClient) readLoop(room *cookingRoom, masterClassID int) {
+	for {
+		var msg CookingMessage
+		if err := c.conn.ReadJSON(&msg); err != nil {
+			return
+		}
+		msg.MasterClassID = masterClassID
+		msg.UserID = c.userID
+		room.broadcast(msg)
+	}
+}
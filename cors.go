@@ -0,0 +1,127 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// corsConfig - настройки CORS, читаемые из окружения при старте
+type corsConfig struct {
+	allowedOrigins   []string
+	allowedMethods   []string
+	allowedHeaders   []string
+	allowCredentials bool
+	maxAge           string
+}
+
+func getEnv(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
+
+func splitEnvList(key string, fallback []string) []string {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return fallback
+	}
+
+	var result []string
+	for _, part := range strings.Split(raw, ",") {
+		if trimmed := strings.TrimSpace(part); trimmed != "" {
+			result = append(result, trimmed)
+		}
+	}
+	return result
+}
+
+// loadCORSConfig читает CORS_ALLOWED_ORIGINS, CORS_ALLOWED_METHODS,
+// CORS_ALLOWED_HEADERS, CORS_ALLOW_CREDENTIALS и CORS_MAX_AGE из окружения,
+// со значениями по умолчанию, подходящими для локальной разработки
+func loadCORSConfig() corsConfig {
+	allowCredentials, err := strconv.ParseBool(getEnv("CORS_ALLOW_CREDENTIALS", "true"))
+	if err != nil {
+		allowCredentials = true
+	}
+
+	return corsConfig{
+		allowedOrigins:   splitEnvList("CORS_ALLOWED_ORIGINS", []string{"http://localhost:5173", "http://localhost:3000"}),
+		allowedMethods:   splitEnvList("CORS_ALLOWED_METHODS", []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}),
+		allowedHeaders:   splitEnvList("CORS_ALLOWED_HEADERS", []string{"Content-Type", "Authorization"}),
+		allowCredentials: allowCredentials,
+		maxAge:           getEnv("CORS_MAX_AGE", "3600"),
+	}
+}
+
+func (c corsConfig) isAllowedOrigin(origin string) bool {
+	for _, allowed := range c.allowedOrigins {
+		if allowed == "*" || allowed == origin {
+			return true
+		}
+	}
+	return false
+}
+
+func (c corsConfig) isAllowedHeader(header string) bool {
+	for _, allowed := range c.allowedHeaders {
+		if strings.EqualFold(allowed, header) {
+			return true
+		}
+	}
+	return false
+}
+
+// filterAllowedHeaders разбирает Access-Control-Request-Headers (список
+// через запятую) и оставляет только заголовки из allow-листа - остальные
+// отбрасываются, а не возвращаются клиенту как разрешённые
+func (c corsConfig) filterAllowedHeaders(requested string) []string {
+	var allowed []string
+	for _, header := range strings.Split(requested, ",") {
+		header = strings.TrimSpace(header)
+		if header != "" && c.isAllowedHeader(header) {
+			allowed = append(allowed, header)
+		}
+	}
+	return allowed
+}
+
+var globalCORSConfig = loadCORSConfig()
+
+// corsMiddleware отражает разрешённый Origin обратно вызывающему (вместо
+// фиксированного "*"), чтобы credentialed-запросы проходили, и отвечает 204
+// на preflight OPTIONS с точными Access-Control-Allow-* заголовками
+func corsMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	cfg := globalCORSConfig
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin != "" && cfg.isAllowedOrigin(origin) {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Set("Vary", "Origin")
+			if cfg.allowCredentials {
+				w.Header().Set("Access-Control-Allow-Credentials", "true")
+			}
+		}
+
+		w.Header().Set("Access-Control-Allow-Methods", strings.Join(cfg.allowedMethods, ", "))
+		w.Header().Set("Access-Control-Max-Age", cfg.maxAge)
+
+		if requested := r.Header.Get("Access-Control-Request-Headers"); requested != "" {
+			if allowed := cfg.filterAllowedHeaders(requested); len(allowed) > 0 {
+				w.Header().Set("Access-Control-Allow-Headers", strings.Join(allowed, ", "))
+			}
+		} else {
+			w.Header().Set("Access-Control-Allow-Headers", strings.Join(cfg.allowedHeaders, ", "))
+		}
+
+		if r.Method == "OPTIONS" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	}
+}
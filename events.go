@@ -0,0 +1,161 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Event - событие, которое интересно подписанным на него пользователям.
+// Публикуются два типа: "new_recipe" (новый рецепт от повара, на которого
+// подписан пользователь) и "waitlist_promoted" (продвижение из очереди
+// ожидания). События о новых мастер-классах и напоминания о записи не
+// публикуются
+type Event struct {
+	Type          string `json:"type"`
+	RecipeID      int    `json:"recipe_id,omitempty"`
+	MasterClassID int    `json:"master_class_id,omitempty"`
+	ChefID        int    `json:"chef_id,omitempty"`
+	Message       string `json:"message,omitempty"`
+}
+
+const eventBufferSize = 16
+
+// EventBus раздаёт события подписанным пользователям по отдельным каналам.
+// Медленные подписчики не блокируют публикацию: при переполненном буфере
+// событие для них отбрасывается, но сама подписка не отключается.
+type EventBus struct {
+	mu   sync.Mutex
+	subs map[int][]chan Event
+}
+
+func newEventBus() *EventBus {
+	return &EventBus{subs: map[int][]chan Event{}}
+}
+
+var globalEventBus = newEventBus()
+
+func (b *EventBus) Subscribe(userID int) chan Event {
+	ch := make(chan Event, eventBufferSize)
+	b.mu.Lock()
+	b.subs[userID] = append(b.subs[userID], ch)
+	b.mu.Unlock()
+	return ch
+}
+
+func (b *EventBus) Unsubscribe(userID int, ch chan Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	channels := b.subs[userID]
+	for i, c := range channels {
+		if c == ch {
+			b.subs[userID] = append(channels[:i], channels[i+1:]...)
+			break
+		}
+	}
+	close(ch)
+}
+
+// Publish отправляет событие каждому каналу подписчика. Если канал
+// переполнен (медленный потребитель), событие для него отбрасывается,
+// чтобы не блокировать публикацию для остальных
+func (b *EventBus) Publish(userID int, event Event) {
+	b.mu.Lock()
+	channels := append([]chan Event{}, b.subs[userID]...)
+	b.mu.Unlock()
+
+	for _, ch := range channels {
+		select {
+		case ch <- event:
+		default:
+			log.Printf("Dropping event for slow subscriber (user %d)", userID)
+		}
+	}
+}
+
+// Close закрывает все подписки - вызывается при штатном завершении процесса
+func (b *EventBus) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for userID, channels := range b.subs {
+		for _, ch := range channels {
+			close(ch)
+		}
+		delete(b.subs, userID)
+	}
+}
+
+func publishEvent(userID int, event Event) {
+	globalEventBus.Publish(userID, event)
+}
+
+// notifySubscribers рассылает событие всем пользователям, подписанным на chefID
+func notifySubscribers(chefID int, event Event) {
+	rows, err := db.Query("SELECT user_id FROM subscriptions WHERE chef_id = ?", chefID)
+	if err != nil {
+		log.Printf("Error notifying subscribers: %v", err)
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var userID int
+		if err := rows.Scan(&userID); err != nil {
+			continue
+		}
+		publishEvent(userID, event)
+	}
+}
+
+const sseHeartbeatInterval = 30 * time.Second
+
+// streamEvents открывает SSE-соединение и стримит JSON-события подписанного
+// пользователя, с периодическими heartbeat-пингами для живости соединения
+func streamEvents(w http.ResponseWriter, r *http.Request) {
+	user, ok := userFromContext(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	ch := globalEventBus.Subscribe(user.ID)
+	defer globalEventBus.Unsubscribe(user.ID, ch)
+
+	heartbeat := time.NewTicker(sseHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case event, open := <-ch:
+			if !open {
+				return
+			}
+			payload, err := json.Marshal(event)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(w, "data: %s\n\n", payload)
+			flusher.Flush()
+		case <-heartbeat.C:
+			fmt.Fprintf(w, ": heartbeat\n\n")
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
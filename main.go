@@ -1,13 +1,18 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
 	"strings"
+	"syscall"
+	"time"
 
 	_ "modernc.org/sqlite"
 )
@@ -68,23 +73,6 @@ type Enrollment struct {
 // База данных
 var db *sql.DB
 
-// CORS middleware
-func corsMiddleware(next http.HandlerFunc) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-		w.Header().Set("Access-Control-Max-Age", "3600")
-
-		if r.Method == "OPTIONS" {
-			w.WriteHeader(http.StatusOK)
-			return
-		}
-
-		next.ServeHTTP(w, r)
-	}
-}
-
 func initDB() {
 	var err error
 
@@ -116,6 +104,10 @@ func initDB() {
 
 	log.Println("Database connected successfully")
 	createTables()
+	createSessionsTable()
+	addAuthColumns()
+	createWaitlistTable()
+	addTranscriptColumn()
 	seedData()
 }
 
@@ -297,11 +289,41 @@ func getRecipes(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(recipes)
 }
 
-func createRecipe(w http.ResponseWriter, r *http.Request) {
-	if r.Method != "POST" {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+// ensureChefForUser возвращает id строки chefs, связанной с этим аккаунтом,
+// создавая её при первом обращении. users.id и chefs.id - разные
+// пространства идентификаторов (таблица chefs существовала до системы
+// аккаунтов), поэтому chef-аккаунт не может просто подставлять свой user.ID
+// в качестве chef_id.
+func ensureChefForUser(userID int) (int, error) {
+	var chefID sql.NullInt64
+	var username string
+	if err := db.QueryRow("SELECT chef_id, username FROM users WHERE id = ?", userID).Scan(&chefID, &username); err != nil {
+		return 0, err
+	}
+	if chefID.Valid {
+		return int(chefID.Int64), nil
+	}
+
+	result, err := db.Exec("INSERT INTO chefs (name) VALUES (?)", username)
+	if err != nil {
+		return 0, err
+	}
+	newChefID, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	if _, err := db.Exec("UPDATE users SET chef_id = ? WHERE id = ?", newChefID, userID); err != nil {
+		return 0, err
+	}
 
-		log.Println("1")
+	return int(newChefID), nil
+}
+
+func createRecipe(w http.ResponseWriter, r *http.Request) {
+	user, ok := userFromContext(r)
+	if !ok || (user.Role != RoleChef && user.Role != RoleAdmin) {
+		http.Error(w, "Only chefs can publish recipes", http.StatusForbidden)
 		return
 	}
 
@@ -312,6 +334,14 @@ func createRecipe(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	chefID, err := ensureChefForUser(user.ID)
+	if err != nil {
+		log.Printf("Error resolving chef record for user %d: %v", user.ID, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	recipe.ChefID = chefID
+
 	ingredientsJSON, _ := json.Marshal(recipe.Ingredients)
 	result, err := db.Exec("INSERT INTO recipes (title, description, ingredients, chef_id, video_url) VALUES (?, ?, ?, ?, ?)",
 		recipe.Title, recipe.Description, string(ingredientsJSON), recipe.ChefID, recipe.VideoURL)
@@ -325,6 +355,9 @@ func createRecipe(w http.ResponseWriter, r *http.Request) {
 
 	id, _ := result.LastInsertId()
 	recipe.ID = int(id)
+	globalRecommender.maybeRebuild()
+	globalSearchIndex.IndexRecipe(recipe.ID, recipe.Title, recipe.Description, recipe.Ingredients)
+	notifySubscribers(recipe.ChefID, Event{Type: "new_recipe", RecipeID: recipe.ID, ChefID: recipe.ChefID, Message: recipe.Title})
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(recipe)
@@ -408,64 +441,6 @@ func getUsers(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(users)
 }
 
-func createUser(w http.ResponseWriter, r *http.Request) {
-	if r.Method != "POST" {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
-	var user User
-	if err := json.NewDecoder(r.Body).Decode(&user); err != nil {
-		http.Error(w, "Invalid JSON", http.StatusBadRequest)
-		return
-	}
-
-	result, err := db.Exec("INSERT INTO users (username, email, preferences) VALUES (?, ?, ?)",
-		user.Username, user.Email, user.Preferences)
-
-	if err != nil {
-		log.Printf("Error creating user: %v", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
-		return
-	}
-
-	id, _ := result.LastInsertId()
-	user.ID = int(id)
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(user)
-}
-
-func generateShoppingList(w http.ResponseWriter, r *http.Request) {
-	recipeID := r.URL.Query().Get("recipe_id")
-	if recipeID == "" {
-		http.Error(w, "recipe_id is required", http.StatusBadRequest)
-		return
-	}
-
-	var ingredientsJSON string
-	err := db.QueryRow("SELECT ingredients FROM recipes WHERE id = ?", recipeID).Scan(&ingredientsJSON)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			http.Error(w, "Recipe not found", http.StatusNotFound)
-		} else {
-			http.Error(w, "Internal server error", http.StatusInternalServerError)
-		}
-		return
-	}
-
-	var ingredients []string
-	json.Unmarshal([]byte(ingredientsJSON), &ingredients)
-
-	response := map[string]interface{}{
-		"recipe_id":     recipeID,
-		"shopping_list": ingredients,
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
-}
-
 func getRecommendations(w http.ResponseWriter, r *http.Request) {
 	userID := r.URL.Query().Get("user_id")
 	if userID == "" {
@@ -534,8 +509,26 @@ func getRecommendations(w http.ResponseWriter, r *http.Request) {
 		recommendations = append(recommendations, mc)
 	}
 
+	userIDInt, _ := strconv.Atoi(userID)
+	recipeRecommendations := globalRecommender.RecommendForUser(userIDInt, 10)
+
+	strategy := recommendationStrategy(r)
+	alpha := recommendationAlpha(r)
+	limit := recommendationLimit(r)
+	mcRecommendations := globalMCRecommender.Recommend(userIDInt, strategy, alpha, limit)
+	if len(mcRecommendations) == 0 {
+		// Холодный старт: у пользователя ещё нет истории для CF/content-скоринга,
+		// отдаём старые рекомендации по подпискам и предпочтениям
+		for _, mc := range recommendations {
+			mcRecommendations = append(mcRecommendations, scoredMasterClass{MasterClass: mc})
+		}
+	}
+
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(recommendations)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"master_classes": mcRecommendations,
+		"recipes":        recipeRecommendations,
+	})
 }
 
 func subscribe(w http.ResponseWriter, r *http.Request) {
@@ -544,11 +537,18 @@ func subscribe(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	user, ok := userFromContext(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
 	var sub Subscription
 	if err := json.NewDecoder(r.Body).Decode(&sub); err != nil {
 		http.Error(w, "Invalid JSON", http.StatusBadRequest)
 		return
 	}
+	sub.UserID = user.ID
 
 	_, err := db.Exec("INSERT OR IGNORE INTO subscriptions (user_id, chef_id) VALUES (?, ?)", sub.UserID, sub.ChefID)
 	if err != nil {
@@ -561,53 +561,6 @@ func subscribe(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(map[string]string{"status": "subscribed"})
 }
 
-func enrollInMasterClass(w http.ResponseWriter, r *http.Request) {
-	if r.Method != "POST" {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
-	var enrollment Enrollment
-	if err := json.NewDecoder(r.Body).Decode(&enrollment); err != nil {
-		http.Error(w, "Invalid JSON", http.StatusBadRequest)
-		return
-	}
-
-	// Проверяем, есть ли места на мастер-классе
-	var currentEnrollments, maxStudents int
-	err := db.QueryRow(`
-		SELECT COUNT(uh.id), mc.max_students 
-		FROM master_classes mc 
-		LEFT JOIN user_history uh ON mc.id = uh.master_class_id 
-		WHERE mc.id = ?
-		GROUP BY mc.id, mc.max_students
-	`, enrollment.MasterClassID).Scan(&currentEnrollments, &maxStudents)
-
-	if err != nil && err != sql.ErrNoRows {
-		log.Printf("Error checking enrollment capacity: %v", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
-		return
-	}
-
-	if currentEnrollments >= maxStudents {
-		http.Error(w, "No available spots", http.StatusConflict)
-		return
-	}
-
-	// Записываем пользователя
-	_, err = db.Exec("INSERT OR IGNORE INTO user_history (user_id, master_class_id) VALUES (?, ?)",
-		enrollment.UserID, enrollment.MasterClassID)
-
-	if err != nil {
-		log.Printf("Error enrolling user: %v", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
-		return
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{"status": "enrolled"})
-}
-
 func getUserHistory(w http.ResponseWriter, r *http.Request) {
 	userID := r.URL.Query().Get("user_id")
 	if userID == "" {
@@ -707,6 +660,7 @@ func getStats(w http.ResponseWriter, r *http.Request) {
 		TotalUsers         int `json:"total_users"`
 		TotalMasterClasses int `json:"total_master_classes"`
 		TotalEnrollments   int `json:"total_enrollments"`
+		CurrentlyCooking   int `json:"currently_cooking"`
 	}
 
 	var stats Stats
@@ -716,95 +670,53 @@ func getStats(w http.ResponseWriter, r *http.Request) {
 	db.QueryRow("SELECT COUNT(*) FROM users").Scan(&stats.TotalUsers)
 	db.QueryRow("SELECT COUNT(*) FROM master_classes").Scan(&stats.TotalMasterClasses)
 	db.QueryRow("SELECT COUNT(*) FROM user_history").Scan(&stats.TotalEnrollments)
+	stats.CurrentlyCooking = globalSessionHub.TotalPresence()
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(stats)
 }
 
-func searchRecipes(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
-	query := r.URL.Query().Get("q")
-	if query == "" {
-		http.Error(w, "search query is required", http.StatusBadRequest)
-		return
-	}
-
-	rows, err := db.Query(`
-		SELECT r.id, r.title, r.description, r.ingredients, r.chef_id, c.name, r.video_url, r.created_at 
-		FROM recipes r 
-		JOIN chefs c ON r.chef_id = c.id
-		WHERE r.title LIKE ? OR r.description LIKE ? OR r.ingredients LIKE ?
-	`, "%"+query+"%", "%"+query+"%", "%"+query+"%")
-
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-	defer rows.Close()
-
-	var recipes []Recipe
-	for rows.Next() {
-		var recipe Recipe
-		var ingredientsJSON string
-		err := rows.Scan(&recipe.ID, &recipe.Title, &recipe.Description, &ingredientsJSON,
-			&recipe.ChefID, &recipe.ChefName, &recipe.VideoURL, &recipe.CreatedAt)
-		if err != nil {
-			continue
-		}
-		json.Unmarshal([]byte(ingredientsJSON), &recipe.Ingredients)
-		recipes = append(recipes, recipe)
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(recipes)
-}
-
 func homePage(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
 	http.ServeFile(w, r, "index.html")
 }
 func main() {
 	initDB()
 	defer db.Close()
 
-	// API маршруты
-	http.HandleFunc("/api/recipes", corsMiddleware(func(w http.ResponseWriter, r *http.Request) {
-		if r.Method == "GET" {
-			getRecipes(w, r)
-		} else if r.Method == "POST" {
-			createRecipe(w, r)
-		}
-	}))
-
-	http.HandleFunc("/api/chefs", corsMiddleware(getChefs))
-	http.HandleFunc("/api/masterclasses", corsMiddleware(getMasterClasses))
-	http.HandleFunc("/api/users", corsMiddleware(func(w http.ResponseWriter, r *http.Request) {
-		if r.Method == "GET" {
-			getUsers(w, r)
-		} else if r.Method == "POST" {
-			createUser(w, r)
-		}
-	}))
+	if err := globalRecommender.Rebuild(); err != nil {
+		log.Printf("Error building initial recommender: %v", err)
+	}
+
+	if err := globalSearchIndex.Rebuild(); err != nil {
+		log.Printf("Error building initial search index: %v", err)
+	}
+	startSearchIndexRebuilder()
 
-	http.HandleFunc("/api/shopping-list", corsMiddleware(generateShoppingList))
-	http.HandleFunc("/api/recommendations", corsMiddleware(getRecommendations))
-	http.HandleFunc("/api/subscribe", corsMiddleware(subscribe))
-	http.HandleFunc("/api/enroll", corsMiddleware(enrollInMasterClass))
-	http.HandleFunc("/api/user-history", corsMiddleware(getUserHistory))
-	http.HandleFunc("/api/user-subscriptions", corsMiddleware(getUserSubscriptions))
-	http.HandleFunc("/api/stats", corsMiddleware(getStats))
-	http.HandleFunc("/api/search", corsMiddleware(searchRecipes))
+	if err := globalMCRecommender.Rebuild(); err != nil {
+		log.Printf("Error building initial master class recommender: %v", err)
+	}
+	startMCRecommenderRebuilder()
+
+	router := newRouter()
+	handler := corsMiddleware(router.ServeHTTP)
 
-	// Главная страница
-	http.HandleFunc("/", corsMiddleware(homePage))
+	server := &http.Server{Addr: ":8080", Handler: handler}
 
-	// Статические файлы (если нужно)
-	http.Handle("/static/", http.StripPrefix("/static/", http.FileServer(http.Dir("./static/"))))
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-stop
+		log.Println("Shutting down...")
+		globalEventBus.Close()
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := server.Shutdown(ctx); err != nil {
+			log.Printf("Error during shutdown: %v", err)
+		}
+	}()
 
 	fmt.Println("🍳 Кулинарная платформа запущена на http://localhost:8080")
-	log.Fatal(http.ListenAndServe(":8080", nil))
+	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		log.Fatal(err)
+	}
 }
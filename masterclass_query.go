@@ -0,0 +1,246 @@
+package main
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Число корзин гистограммы на числовое измерение
+const histogramBuckets = 20
+
+const histogramCacheTTL = 30 * time.Second
+
+// masterClassQuery описывает многомерный фильтр для поиска мастер-классов
+type masterClassQuery struct {
+	PriceMin      *int     `json:"price_min"`
+	PriceMax      *int     `json:"price_max"`
+	DurationMin   *int     `json:"duration_min"`
+	DurationMax   *int     `json:"duration_max"`
+	ChefRatingMin *float64 `json:"chef_rating_min"`
+	ChefRatingMax *float64 `json:"chef_rating_max"`
+	DateFrom      string   `json:"date_from"`
+	DateTo        string   `json:"date_to"`
+	Specialities  []string `json:"specialities"`
+	Query         string   `json:"query"`
+}
+
+type histogramBucket struct {
+	From  float64 `json:"from"`
+	To    float64 `json:"to"`
+	Count int     `json:"count"`
+}
+
+type masterClassQueryResponse struct {
+	Results    []MasterClass                `json:"results"`
+	Histograms map[string][]histogramBucket `json:"histograms"`
+}
+
+type histogramCacheEntry struct {
+	response  masterClassQueryResponse
+	expiresAt time.Time
+}
+
+var histogramCacheMu sync.Mutex
+var histogramCache = map[string]histogramCacheEntry{}
+
+// querySignature идентифицирует запрос для короткоживущего кэша гистограмм,
+// чтобы одинаковые фильтры не пересчитывались на каждый рендер слайдера
+func querySignature(q masterClassQuery) string {
+	raw, _ := json.Marshal(q)
+	sum := sha1.Sum(raw)
+	return hex.EncodeToString(sum[:])
+}
+
+func queryMasterClasses(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var q masterClassQuery
+	if err := json.NewDecoder(r.Body).Decode(&q); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	sig := querySignature(q)
+
+	histogramCacheMu.Lock()
+	if entry, ok := histogramCache[sig]; ok && time.Now().Before(entry.expiresAt) {
+		histogramCacheMu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(entry.response)
+		return
+	}
+	histogramCacheMu.Unlock()
+
+	results, err := runMasterClassQuery(q)
+	if err != nil {
+		log.Printf("Error running masterclass query: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	histograms := map[string][]histogramBucket{
+		"price":       numericHistogram(q, "price", q.PriceMin, q.PriceMax, 0, 20000),
+		"duration":    numericHistogram(q, "duration", q.DurationMin, q.DurationMax, 0, 480),
+		"chef_rating": floatHistogram(q, "chef_rating", q.ChefRatingMin, q.ChefRatingMax, 0, 5),
+	}
+
+	response := masterClassQueryResponse{Results: results, Histograms: histograms}
+
+	histogramCacheMu.Lock()
+	histogramCache[sig] = histogramCacheEntry{response: response, expiresAt: time.Now().Add(histogramCacheTTL)}
+	histogramCacheMu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// buildMasterClassWhere собирает WHERE и аргументы для запроса q, опционально
+// заменяя ограничение по измерению override другим диапазоном [overrideFrom, overrideTo]
+func buildMasterClassWhere(q masterClassQuery, override string, overrideFrom, overrideTo float64) (string, []interface{}) {
+	conditions := []string{}
+	args := []interface{}{}
+
+	addRange := func(dim, column string, min, max *int) {
+		if dim == override {
+			conditions = append(conditions, column+" >= ? AND "+column+" <= ?")
+			args = append(args, overrideFrom, overrideTo)
+			return
+		}
+		if min != nil {
+			conditions = append(conditions, column+" >= ?")
+			args = append(args, *min)
+		}
+		if max != nil {
+			conditions = append(conditions, column+" <= ?")
+			args = append(args, *max)
+		}
+	}
+
+	addRange("price", "mc.price", q.PriceMin, q.PriceMax)
+	addRange("duration", "mc.duration", q.DurationMin, q.DurationMax)
+
+	if override == "chef_rating" {
+		conditions = append(conditions, "c.rating >= ? AND c.rating <= ?")
+		args = append(args, overrideFrom, overrideTo)
+	} else {
+		if q.ChefRatingMin != nil {
+			conditions = append(conditions, "c.rating >= ?")
+			args = append(args, *q.ChefRatingMin)
+		}
+		if q.ChefRatingMax != nil {
+			conditions = append(conditions, "c.rating <= ?")
+			args = append(args, *q.ChefRatingMax)
+		}
+	}
+
+	if q.DateFrom != "" {
+		conditions = append(conditions, "mc.datetime >= ?")
+		args = append(args, q.DateFrom)
+	}
+	if q.DateTo != "" {
+		conditions = append(conditions, "mc.datetime <= ?")
+		args = append(args, q.DateTo)
+	}
+
+	if len(q.Specialities) > 0 {
+		placeholders := strings.Repeat("?,", len(q.Specialities)-1) + "?"
+		conditions = append(conditions, "c.speciality IN ("+placeholders+")")
+		for _, s := range q.Specialities {
+			args = append(args, s)
+		}
+	}
+
+	if q.Query != "" {
+		conditions = append(conditions, "(mc.title LIKE ? OR mc.description LIKE ?)")
+		args = append(args, "%"+q.Query+"%", "%"+q.Query+"%")
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = " WHERE " + strings.Join(conditions, " AND ")
+	}
+	return where, args
+}
+
+func runMasterClassQuery(q masterClassQuery) ([]MasterClass, error) {
+	where, args := buildMasterClassWhere(q, "", 0, 0)
+
+	query := `
+		SELECT mc.id, mc.title, mc.chef_id, c.name, mc.datetime, mc.duration, mc.price, mc.max_students, mc.description
+		FROM master_classes mc
+		JOIN chefs c ON mc.chef_id = c.id` + where + `
+		ORDER BY mc.datetime`
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []MasterClass
+	for rows.Next() {
+		var mc MasterClass
+		if err := rows.Scan(&mc.ID, &mc.Title, &mc.ChefID, &mc.ChefName, &mc.DateTime, &mc.Duration, &mc.Price, &mc.MaxStudents, &mc.Description); err != nil {
+			continue
+		}
+		results = append(results, mc)
+	}
+	return results, nil
+}
+
+func countMasterClasses(q masterClassQuery, dim string, from, to float64) int {
+	where, args := buildMasterClassWhere(q, dim, from, to)
+	query := fmt.Sprintf(`
+		SELECT COUNT(*)
+		FROM master_classes mc
+		JOIN chefs c ON mc.chef_id = c.id%s`, where)
+
+	var count int
+	if err := db.QueryRow(query, args...).Scan(&count); err != nil {
+		return 0
+	}
+	return count
+}
+
+// numericHistogram делит [lo, hi] на histogramBuckets интервалов и для каждого
+// считает, сколько результатов было бы при подстановке именно этого интервала
+// вместо текущего ограничения по dim - предпросмотр "сколько будет, если подвинуть слайдер"
+func numericHistogram(q masterClassQuery, dim string, min, max *int, lo, hi int) []histogramBucket {
+	step := float64(hi-lo) / float64(histogramBuckets)
+	buckets := make([]histogramBucket, 0, histogramBuckets)
+	for i := 0; i < histogramBuckets; i++ {
+		from := float64(lo) + float64(i)*step
+		to := from + step
+		buckets = append(buckets, histogramBucket{
+			From:  from,
+			To:    to,
+			Count: countMasterClasses(q, dim, from, to),
+		})
+	}
+	return buckets
+}
+
+func floatHistogram(q masterClassQuery, dim string, min, max *float64, lo, hi float64) []histogramBucket {
+	step := (hi - lo) / float64(histogramBuckets)
+	buckets := make([]histogramBucket, 0, histogramBuckets)
+	for i := 0; i < histogramBuckets; i++ {
+		from := lo + float64(i)*step
+		to := from + step
+		buckets = append(buckets, histogramBucket{
+			From:  from,
+			To:    to,
+			Count: countMasterClasses(q, dim, from, to),
+		})
+	}
+	return buckets
+}
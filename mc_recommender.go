@@ -0,0 +1,320 @@
+package main
+
+import (
+	"log"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// masterClassCF хранит item-based CF матрицу сходства мастер-классов,
+// построенную по совместной посещаемости в user_history, и content-векторы
+// (title+description+специализация повара) для гибридного скоринга
+type masterClassCF struct {
+	mu         sync.RWMutex
+	userItems  map[int]map[int]bool    // userID -> посещённые master_class_id
+	similarity map[int]map[int]float64 // master_class_id -> master_class_id -> shrunk cosine
+	content    map[int]map[string]float64
+}
+
+var globalMCRecommender = &masterClassCF{
+	userItems:  map[int]map[int]bool{},
+	similarity: map[int]map[int]float64{},
+	content:    map[int]map[string]float64{},
+}
+
+// cfShrinkage ослабляет сходство пар с малым числом совместных посещений,
+// чтобы случайное совпадение двух пользователей не давало сходству 1.0
+const cfShrinkage = 5.0
+
+const mcRebuildInterval = 5 * time.Minute
+
+// defaultHybridAlpha - вес CF-скора в гибридной смеси, если ?alpha= не задан
+const defaultHybridAlpha = 0.5
+
+// Rebuild пересобирает матрицу сходства мастер-классов и content-векторы по
+// текущему содержимому user_history и master_classes
+func (cf *masterClassCF) Rebuild() error {
+	userItems, itemUsers, err := loadInteractions()
+	if err != nil {
+		return err
+	}
+
+	similarity := buildItemSimilarity(itemUsers)
+
+	content, err := buildMasterClassContentVectors()
+	if err != nil {
+		return err
+	}
+
+	cf.mu.Lock()
+	cf.userItems = userItems
+	cf.similarity = similarity
+	cf.content = content
+	cf.mu.Unlock()
+
+	return nil
+}
+
+func loadInteractions() (map[int]map[int]bool, map[int]map[int]bool, error) {
+	rows, err := db.Query("SELECT user_id, master_class_id FROM user_history")
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	userItems := map[int]map[int]bool{}
+	itemUsers := map[int]map[int]bool{}
+
+	for rows.Next() {
+		var userID, masterClassID int
+		if err := rows.Scan(&userID, &masterClassID); err != nil {
+			continue
+		}
+
+		if userItems[userID] == nil {
+			userItems[userID] = map[int]bool{}
+		}
+		userItems[userID][masterClassID] = true
+
+		if itemUsers[masterClassID] == nil {
+			itemUsers[masterClassID] = map[int]bool{}
+		}
+		itemUsers[masterClassID][userID] = true
+	}
+
+	return userItems, itemUsers, nil
+}
+
+// buildItemSimilarity считает косинусное сходство между мастер-классами по
+// множествам пользователей, посетивших каждый из них, со сглаживанием
+// (shrinkage) по числу совместных посещений
+func buildItemSimilarity(itemUsers map[int]map[int]bool) map[int]map[int]float64 {
+	coOccurrence := map[int]map[int]int{}
+	for itemA, usersA := range itemUsers {
+		for userID := range usersA {
+			for itemB, usersB := range itemUsers {
+				if itemB == itemA || !usersB[userID] {
+					continue
+				}
+				if coOccurrence[itemA] == nil {
+					coOccurrence[itemA] = map[int]int{}
+				}
+				coOccurrence[itemA][itemB]++
+			}
+		}
+	}
+
+	similarity := map[int]map[int]float64{}
+	for itemA, counts := range coOccurrence {
+		similarity[itemA] = map[int]float64{}
+		for itemB, count := range counts {
+			cosine := float64(count) / math.Sqrt(float64(len(itemUsers[itemA]))*float64(len(itemUsers[itemB])))
+			shrinkFactor := float64(count) / (float64(count) + cfShrinkage)
+			similarity[itemA][itemB] = cosine * shrinkFactor
+		}
+	}
+	return similarity
+}
+
+// buildMasterClassContentVectors строит TF-IDF векторы мастер-классов по
+// названию, описанию и специализации повода - это наш заменитель
+// "ингредиентов/кухни/тегов" рецептов, которых у мастер-классов нет
+func buildMasterClassContentVectors() (map[int]map[string]float64, error) {
+	rows, err := db.Query(`
+		SELECT mc.id, mc.title, mc.description, c.speciality
+		FROM master_classes mc
+		JOIN chefs c ON mc.chef_id = c.id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	type doc struct {
+		id     int
+		tokens []string
+	}
+
+	var docs []doc
+	df := map[string]int{}
+
+	for rows.Next() {
+		var id int
+		var title, description, speciality string
+		if err := rows.Scan(&id, &title, &description, &speciality); err != nil {
+			continue
+		}
+
+		tokens := tokenize(title + " " + description + " " + speciality)
+		docs = append(docs, doc{id: id, tokens: tokens})
+
+		seen := map[string]bool{}
+		for _, t := range tokens {
+			if !seen[t] {
+				df[t]++
+				seen[t] = true
+			}
+		}
+	}
+
+	n := float64(len(docs))
+	idf := map[string]float64{}
+	for term, count := range df {
+		idf[term] = math.Log(1 + n/float64(count))
+	}
+
+	vectors := map[int]map[string]float64{}
+	for _, d := range docs {
+		if len(d.tokens) == 0 {
+			vectors[d.id] = map[string]float64{}
+			continue
+		}
+		tf := map[string]float64{}
+		for _, t := range d.tokens {
+			tf[t]++
+		}
+		vec := map[string]float64{}
+		for term, freq := range tf {
+			vec[term] = (freq / float64(len(d.tokens))) * idf[term]
+		}
+		vectors[d.id] = vec
+	}
+
+	return vectors, nil
+}
+
+// startMCRecommenderRebuilder пересчитывает CF-матрицу и content-векторы в
+// фоне на фиксированном интервале, т.к. посещаемость меняется непрерывно
+func startMCRecommenderRebuilder() {
+	go func() {
+		ticker := time.NewTicker(mcRebuildInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if err := globalMCRecommender.Rebuild(); err != nil {
+				log.Printf("Error rebuilding master class CF recommender: %v", err)
+			}
+		}
+	}()
+}
+
+type scoredMasterClass struct {
+	MasterClass MasterClass `json:"master_class"`
+	Score       float64     `json:"score"`
+}
+
+// cfScore суммирует сходство кандидата с каждым мастер-классом, уже
+// посещённым пользователем - классический item-based CF скор
+func (cf *masterClassCF) cfScore(userID, candidateID int) float64 {
+	var score float64
+	for itemID := range cf.userItems[userID] {
+		score += cf.similarity[itemID][candidateID]
+	}
+	return score
+}
+
+// contentScore усредняет косинусное сходство кандидата с мастер-классами из
+// истории пользователя
+func (cf *masterClassCF) contentScore(userID, candidateID int) float64 {
+	visited := cf.userItems[userID]
+	if len(visited) == 0 {
+		return 0
+	}
+
+	var total float64
+	for itemID := range visited {
+		total += cosineSimilarity(cf.content[candidateID], cf.content[itemID])
+	}
+	return total / float64(len(visited))
+}
+
+// Recommend ранжирует непосещённые мастер-классы для пользователя по
+// выбранной стратегии: cf (чистый collaborative filtering), content
+// (content-based по общим словам) или hybrid (их линейная смесь с весом alpha)
+func (cf *masterClassCF) Recommend(userID int, strategy string, alpha float64, limit int) []scoredMasterClass {
+	cf.mu.RLock()
+	defer cf.mu.RUnlock()
+
+	visited := cf.userItems[userID]
+
+	var scored []scoredRecipeID
+	for candidateID := range cf.content {
+		if visited[candidateID] {
+			continue
+		}
+
+		var score float64
+		switch strategy {
+		case "cf":
+			score = cf.cfScore(userID, candidateID)
+		case "content":
+			score = cf.contentScore(userID, candidateID)
+		default:
+			score = alpha*cf.cfScore(userID, candidateID) + (1-alpha)*cf.contentScore(userID, candidateID)
+		}
+
+		if score > 0 {
+			scored = append(scored, scoredRecipeID{id: candidateID, score: score})
+		}
+	}
+
+	sort.Slice(scored, func(i, j int) bool { return scored[i].score > scored[j].score })
+	if len(scored) > limit {
+		scored = scored[:limit]
+	}
+
+	result := make([]scoredMasterClass, 0, len(scored))
+	for _, s := range scored {
+		mc, ok := loadMasterClassByID(s.id)
+		if !ok {
+			continue
+		}
+		result = append(result, scoredMasterClass{MasterClass: mc, Score: s.score})
+	}
+	return result
+}
+
+func loadMasterClassByID(id int) (MasterClass, bool) {
+	var mc MasterClass
+	err := db.QueryRow(`
+		SELECT mc.id, mc.title, mc.chef_id, c.name, mc.datetime, mc.duration, mc.price, mc.max_students, mc.description
+		FROM master_classes mc
+		JOIN chefs c ON mc.chef_id = c.id
+		WHERE mc.id = ?
+	`, id).Scan(&mc.ID, &mc.Title, &mc.ChefID, &mc.ChefName, &mc.DateTime, &mc.Duration, &mc.Price, &mc.MaxStudents, &mc.Description)
+	if err != nil {
+		return MasterClass{}, false
+	}
+	return mc, true
+}
+
+// recommendationStrategy парсит и валидирует ?strategy=, по умолчанию hybrid
+func recommendationStrategy(r *http.Request) string {
+	switch strategy := r.URL.Query().Get("strategy"); strategy {
+	case "cf", "content":
+		return strategy
+	default:
+		return "hybrid"
+	}
+}
+
+func recommendationAlpha(r *http.Request) float64 {
+	if raw := r.URL.Query().Get("alpha"); raw != "" {
+		if parsed, err := strconv.ParseFloat(raw, 64); err == nil && parsed >= 0 && parsed <= 1 {
+			return parsed
+		}
+	}
+	return defaultHybridAlpha
+}
+
+func recommendationLimit(r *http.Request) int {
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		if parsed, err := strconv.Atoi(raw); err == nil && parsed > 0 {
+			return parsed
+		}
+	}
+	return 10
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+// TestCreateRecipe_PublishedByChefIsReachable покрывает регрессию, когда
+// createRecipe писал recipe.ChefID = user.ID (id пользователя), хотя chefs
+// ведёт собственную нумерацию - из-за этого опубликованный рецепт пропадал
+// из всех выборок, использующих INNER JOIN chefs.
+func TestCreateRecipe_PublishedByChefIsReachable(t *testing.T) {
+	setupTestDB(t)
+
+	// Регистрируем первого (обычного) пользователя, чтобы id поварского
+	// аккаунта ниже не совпал с id его будущей строки в chefs случайно
+	doJSONRequest(register, "POST", "/api/v1/register", registerRequest{
+		Username: "plain_user",
+		Password: "correct-horse",
+		Email:    "plain@example.com",
+	})
+
+	regResp := doJSONRequest(register, "POST", "/api/v1/register", registerRequest{
+		Username: "chef_daria",
+		Password: "correct-horse",
+		Email:    "daria@example.com",
+	})
+	if regResp.Code != http.StatusOK {
+		t.Fatalf("register: expected 200, got %d: %s", regResp.Code, regResp.Body.String())
+	}
+
+	var chefUserID int
+	if err := db.QueryRow("SELECT id FROM users WHERE username = ?", "chef_daria").Scan(&chefUserID); err != nil {
+		t.Fatalf("Error reading registered user id: %v", err)
+	}
+	if _, err := db.Exec("UPDATE users SET role = ? WHERE id = ?", RoleChef, chefUserID); err != nil {
+		t.Fatalf("Error promoting user to chef: %v", err)
+	}
+
+	recipe := Recipe{
+		Title:       "Тест-драйв супа",
+		Description: "Рецепт, опубликованный поваром в тесте",
+		Ingredients: []string{"вода", "соль"},
+		VideoURL:    "https://example.com/video",
+	}
+	body, _ := json.Marshal(recipe)
+	createReq := httptest.NewRequest("POST", "/api/v1/recipes", bytes.NewReader(body))
+	createReq = withAuthUser(createReq, &AuthUser{ID: chefUserID, Role: RoleChef})
+	w := httptest.NewRecorder()
+	createRecipe(w, createReq)
+	if w.Code != http.StatusOK {
+		t.Fatalf("createRecipe: expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var created Recipe
+	if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil {
+		t.Fatalf("Error decoding created recipe: %v", err)
+	}
+	if created.ChefID == chefUserID {
+		t.Fatalf("recipe.ChefID (%d) reused the user's id instead of a real chefs.id", created.ChefID)
+	}
+
+	var chefCount int
+	db.QueryRow("SELECT COUNT(*) FROM chefs WHERE id = ?", created.ChefID).Scan(&chefCount)
+	if chefCount != 1 {
+		t.Fatalf("expected a chefs row for id %d, found %d", created.ChefID, chefCount)
+	}
+
+	listResp := httptest.NewRecorder()
+	getRecipes(listResp, httptest.NewRequest("GET", "/api/v1/recipes", nil))
+	var recipes []Recipe
+	json.Unmarshal(listResp.Body.Bytes(), &recipes)
+	if !containsRecipeID(recipes, created.ID) {
+		t.Fatal("published recipe did not show up in getRecipes (INNER JOIN chefs dropped it)")
+	}
+
+	chefRecipesReq := httptest.NewRequest("GET", "/api/v1/chefs/"+strconv.Itoa(created.ChefID)+"/recipes", nil)
+	chefRecipesReq = mux.SetURLVars(chefRecipesReq, map[string]string{"id": strconv.Itoa(created.ChefID)})
+	chefRecipesResp := httptest.NewRecorder()
+	getChefRecipes(chefRecipesResp, chefRecipesReq)
+	var chefRecipes []Recipe
+	json.Unmarshal(chefRecipesResp.Body.Bytes(), &chefRecipes)
+	if !containsRecipeID(chefRecipes, created.ID) {
+		t.Fatal("published recipe did not show up in getChefRecipes")
+	}
+}
+
+func containsRecipeID(recipes []Recipe, id int) bool {
+	for _, recipe := range recipes {
+		if recipe.ID == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,286 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"math"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// recommender хранит TF-IDF векторы рецептов в памяти и пересобирает их
+// при появлении новых рецептов
+type recommender struct {
+	mu           sync.RWMutex
+	vectors      map[int]map[string]float64 // recipeID -> term -> tf-idf вес
+	idf          map[string]float64
+	sinceRebuild int
+}
+
+var globalRecommender = &recommender{
+	vectors: map[int]map[string]float64{},
+	idf:     map[string]float64{},
+}
+
+// rebuildThreshold - на сколько должно измениться число рецептов, чтобы
+// фоновая горутина пересобрала IDF-таблицу
+const rebuildThreshold = 3
+
+var tokenPattern = regexp.MustCompile(`[a-zа-яё0-9]+`)
+
+func tokenize(text string) []string {
+	return tokenPattern.FindAllString(strings.ToLower(text), -1)
+}
+
+type scoredRecipeID struct {
+	id    int
+	score float64
+}
+
+// Rebuild перестраивает TF-IDF векторы по всем рецептам в базе
+func (rc *recommender) Rebuild() error {
+	rows, err := db.Query("SELECT id, title, description, ingredients FROM recipes")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	type doc struct {
+		id     int
+		tokens []string
+	}
+
+	var docs []doc
+	df := map[string]int{}
+
+	for rows.Next() {
+		var id int
+		var title, description, ingredientsJSON string
+		if err := rows.Scan(&id, &title, &description, &ingredientsJSON); err != nil {
+			continue
+		}
+
+		var ingredients []string
+		json.Unmarshal([]byte(ingredientsJSON), &ingredients)
+
+		tokens := tokenize(title + " " + description + " " + strings.Join(ingredients, " "))
+		docs = append(docs, doc{id: id, tokens: tokens})
+
+		seen := map[string]bool{}
+		for _, t := range tokens {
+			if !seen[t] {
+				df[t]++
+				seen[t] = true
+			}
+		}
+	}
+
+	n := float64(len(docs))
+	idf := map[string]float64{}
+	for term, count := range df {
+		idf[term] = math.Log(1 + n/float64(count))
+	}
+
+	vectors := map[int]map[string]float64{}
+	for _, d := range docs {
+		tf := map[string]float64{}
+		for _, t := range d.tokens {
+			tf[t]++
+		}
+		vec := map[string]float64{}
+		for term, freq := range tf {
+			vec[term] = (freq / float64(len(d.tokens))) * idf[term]
+		}
+		vectors[d.id] = vec
+	}
+
+	rc.mu.Lock()
+	rc.idf = idf
+	rc.vectors = vectors
+	rc.sinceRebuild = 0
+	rc.mu.Unlock()
+
+	return nil
+}
+
+// maybeRebuild вызывается после создания рецепта и запускает фоновую
+// пересборку, когда число новых рецептов с прошлой пересборки превышает порог
+func (rc *recommender) maybeRebuild() {
+	rc.mu.Lock()
+	rc.sinceRebuild++
+	shouldRebuild := rc.sinceRebuild > rebuildThreshold
+	rc.mu.Unlock()
+
+	if shouldRebuild {
+		go func() {
+			if err := rc.Rebuild(); err != nil {
+				log.Printf("Error rebuilding recommender: %v", err)
+			}
+		}()
+	}
+}
+
+func cosineSimilarity(a, b map[string]float64) float64 {
+	var dot, normA, normB float64
+	for term, weight := range a {
+		dot += weight * b[term]
+		normA += weight * weight
+	}
+	for _, weight := range b {
+		normB += weight * weight
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
+
+type scoredRecipe struct {
+	Recipe Recipe  `json:"recipe"`
+	Score  float64 `json:"score"`
+}
+
+func topScored(scored []scoredRecipeID, k int) []scoredRecipe {
+	sort.Slice(scored, func(i, j int) bool { return scored[i].score > scored[j].score })
+	if len(scored) > k {
+		scored = scored[:k]
+	}
+
+	result := make([]scoredRecipe, 0, len(scored))
+	for _, p := range scored {
+		recipe, ok := loadRecipeByID(p.id)
+		if !ok {
+			continue
+		}
+		result = append(result, scoredRecipe{Recipe: recipe, Score: p.score})
+	}
+	return result
+}
+
+// Similar возвращает top-K рецептов, наиболее похожих на recipeID по косинусному
+// сходству TF-IDF векторов
+func (rc *recommender) Similar(recipeID, k int) []scoredRecipe {
+	rc.mu.RLock()
+	target, ok := rc.vectors[recipeID]
+	if !ok {
+		rc.mu.RUnlock()
+		return nil
+	}
+
+	var scored []scoredRecipeID
+	for id, vec := range rc.vectors {
+		if id == recipeID {
+			continue
+		}
+		scored = append(scored, scoredRecipeID{id: id, score: cosineSimilarity(target, vec)})
+	}
+	rc.mu.RUnlock()
+
+	return topScored(scored, k)
+}
+
+// RecommendForUser строит профиль пользователя как среднее векторов рецептов,
+// связанных с его посещёнными мастер-классами и подписками на поваров, и
+// ранжирует по сходству с этим профилем все остальные рецепты (рецепты,
+// вошедшие в профиль, в выдачу не попадают)
+func (rc *recommender) RecommendForUser(userID, k int) []scoredRecipe {
+	rows, err := db.Query(`
+		SELECT DISTINCT r.id
+		FROM recipes r
+		JOIN master_classes mc ON mc.chef_id = r.chef_id
+		JOIN user_history uh ON uh.master_class_id = mc.id
+		WHERE uh.user_id = ?
+		UNION
+		SELECT DISTINCT r.id
+		FROM recipes r
+		JOIN subscriptions s ON s.chef_id = r.chef_id
+		WHERE s.user_id = ?
+	`, userID, userID)
+	if err != nil {
+		log.Printf("Error building user profile: %v", err)
+		return nil
+	}
+	defer rows.Close()
+
+	var profileRecipeIDs []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			continue
+		}
+		profileRecipeIDs = append(profileRecipeIDs, id)
+	}
+
+	if len(profileRecipeIDs) == 0 {
+		return nil
+	}
+
+	rc.mu.RLock()
+	defer rc.mu.RUnlock()
+
+	profile := map[string]float64{}
+	for _, id := range profileRecipeIDs {
+		for term, weight := range rc.vectors[id] {
+			profile[term] += weight
+		}
+	}
+	for term := range profile {
+		profile[term] /= float64(len(profileRecipeIDs))
+	}
+
+	seen := map[int]bool{}
+	for _, id := range profileRecipeIDs {
+		seen[id] = true
+	}
+
+	var scored []scoredRecipeID
+	for id, vec := range rc.vectors {
+		if seen[id] {
+			continue
+		}
+		scored = append(scored, scoredRecipeID{id: id, score: cosineSimilarity(profile, vec)})
+	}
+
+	return topScored(scored, k)
+}
+
+func loadRecipeByID(id int) (Recipe, bool) {
+	var recipe Recipe
+	var ingredientsJSON string
+	err := db.QueryRow(`
+		SELECT r.id, r.title, r.description, r.ingredients, r.chef_id, c.name, r.video_url, r.created_at
+		FROM recipes r
+		JOIN chefs c ON r.chef_id = c.id
+		WHERE r.id = ?
+	`, id).Scan(&recipe.ID, &recipe.Title, &recipe.Description, &ingredientsJSON,
+		&recipe.ChefID, &recipe.ChefName, &recipe.VideoURL, &recipe.CreatedAt)
+	if err != nil {
+		return Recipe{}, false
+	}
+	json.Unmarshal([]byte(ingredientsJSON), &recipe.Ingredients)
+	return recipe, true
+}
+
+func getSimilarRecipes(w http.ResponseWriter, r *http.Request) {
+	recipeID, err := idFromPath(r)
+	if err != nil {
+		http.Error(w, "Invalid recipe id", http.StatusBadRequest)
+		return
+	}
+
+	k := 5
+	if kStr := r.URL.Query().Get("k"); kStr != "" {
+		if parsed, err := strconv.Atoi(kStr); err == nil && parsed > 0 {
+			k = parsed
+		}
+	}
+
+	similar := globalRecommender.Similar(recipeID, k)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(similar)
+}
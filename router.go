@@ -0,0 +1,200 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// idFromPath извлекает числовой {id} из path-параметров роутера
+func idFromPath(r *http.Request) (int, error) {
+	return strconv.Atoi(mux.Vars(r)["id"])
+}
+
+func jsonError(w http.ResponseWriter, message string, status int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
+
+func notFoundHandler(w http.ResponseWriter, r *http.Request) {
+	jsonError(w, "Not found", http.StatusNotFound)
+}
+
+func methodNotAllowedHandler(w http.ResponseWriter, r *http.Request) {
+	jsonError(w, "Method not allowed", http.StatusMethodNotAllowed)
+}
+
+// legacyAPIRedirect перенаправляет старые маршруты /api/* на их версионированный
+// эквивалент /api/v1/*, сохраняя метод и тело запроса (308)
+func legacyAPIRedirect(w http.ResponseWriter, r *http.Request) {
+	target := "/api/v1" + strings.TrimPrefix(r.URL.Path, "/api")
+	if r.URL.RawQuery != "" {
+		target += "?" + r.URL.RawQuery
+	}
+	http.Redirect(w, r, target, http.StatusPermanentRedirect)
+}
+
+func getRecipeByID(w http.ResponseWriter, r *http.Request) {
+	id, err := idFromPath(r)
+	if err != nil {
+		jsonError(w, "Invalid recipe id", http.StatusBadRequest)
+		return
+	}
+
+	recipe, ok := loadRecipeByID(id)
+	if !ok {
+		jsonError(w, "Recipe not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(recipe)
+}
+
+func getChefByID(w http.ResponseWriter, r *http.Request) {
+	id, err := idFromPath(r)
+	if err != nil {
+		jsonError(w, "Invalid chef id", http.StatusBadRequest)
+		return
+	}
+
+	var chef Chef
+	err = db.QueryRow("SELECT id, name, speciality, rating, avatar, description FROM chefs WHERE id = ?", id).
+		Scan(&chef.ID, &chef.Name, &chef.Speciality, &chef.Rating, &chef.Avatar, &chef.Description)
+	if err != nil {
+		jsonError(w, "Chef not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(chef)
+}
+
+func getChefRecipes(w http.ResponseWriter, r *http.Request) {
+	id, err := idFromPath(r)
+	if err != nil {
+		jsonError(w, "Invalid chef id", http.StatusBadRequest)
+		return
+	}
+
+	rows, err := db.Query(`
+		SELECT r.id, r.title, r.description, r.ingredients, r.chef_id, c.name, r.video_url, r.created_at
+		FROM recipes r
+		JOIN chefs c ON r.chef_id = c.id
+		WHERE r.chef_id = ?
+	`, id)
+	if err != nil {
+		jsonError(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	var recipes []Recipe
+	for rows.Next() {
+		var recipe Recipe
+		var ingredientsJSON string
+		if err := rows.Scan(&recipe.ID, &recipe.Title, &recipe.Description, &ingredientsJSON,
+			&recipe.ChefID, &recipe.ChefName, &recipe.VideoURL, &recipe.CreatedAt); err != nil {
+			continue
+		}
+		json.Unmarshal([]byte(ingredientsJSON), &recipe.Ingredients)
+		recipes = append(recipes, recipe)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(recipes)
+}
+
+func getMasterClassByID(w http.ResponseWriter, r *http.Request) {
+	id, err := idFromPath(r)
+	if err != nil {
+		jsonError(w, "Invalid master class id", http.StatusBadRequest)
+		return
+	}
+
+	var mc MasterClass
+	err = db.QueryRow(`
+		SELECT mc.id, mc.title, mc.chef_id, c.name, mc.datetime, mc.duration, mc.price, mc.max_students, mc.description
+		FROM master_classes mc
+		JOIN chefs c ON mc.chef_id = c.id
+		WHERE mc.id = ?
+	`, id).Scan(&mc.ID, &mc.Title, &mc.ChefID, &mc.ChefName, &mc.DateTime, &mc.Duration, &mc.Price, &mc.MaxStudents, &mc.Description)
+	if err != nil {
+		jsonError(w, "Master class not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(mc)
+}
+
+func getUserHistoryByID(w http.ResponseWriter, r *http.Request) {
+	id, err := idFromPath(r)
+	if err != nil {
+		jsonError(w, "Invalid user id", http.StatusBadRequest)
+		return
+	}
+
+	q := r.URL.Query()
+	q.Set("user_id", strconv.Itoa(id))
+	r.URL.RawQuery = q.Encode()
+	getUserHistory(w, r)
+}
+
+// newRouter собирает REST-маршруты платформы под /api/v1/... с path-параметрами
+// вместо query-строк (?user_id=, ?recipe_id=) и единой JSON-обработкой 404/405
+func newRouter() *mux.Router {
+	r := mux.NewRouter()
+	r.NotFoundHandler = http.HandlerFunc(notFoundHandler)
+	r.MethodNotAllowedHandler = http.HandlerFunc(methodNotAllowedHandler)
+
+	v1 := r.PathPrefix("/api/v1").Subrouter()
+
+	v1.HandleFunc("/recipes", getRecipes).Methods("GET")
+	v1.HandleFunc("/recipes", authMiddleware(createRecipe)).Methods("POST")
+	v1.HandleFunc("/recipes/{id}", getRecipeByID).Methods("GET")
+	v1.HandleFunc("/recipes/{id}/similar", getSimilarRecipes).Methods("GET")
+
+	v1.HandleFunc("/chefs", getChefs).Methods("GET")
+	v1.HandleFunc("/chefs/{id}", getChefByID).Methods("GET")
+	v1.HandleFunc("/chefs/{id}/recipes", getChefRecipes).Methods("GET")
+
+	v1.HandleFunc("/masterclasses", getMasterClasses).Methods("GET")
+	v1.HandleFunc("/masterclasses/query", queryMasterClasses).Methods("POST")
+	v1.HandleFunc("/masterclasses/{id:[0-9]+}", getMasterClassByID).Methods("GET")
+	v1.HandleFunc("/masterclasses/{id:[0-9]+}/roster", getMasterClassRoster).Methods("GET")
+
+	v1.HandleFunc("/users", getUsers).Methods("GET")
+	v1.HandleFunc("/users/{id}/history", getUserHistoryByID).Methods("GET")
+
+	v1.HandleFunc("/register", register).Methods("POST")
+	v1.HandleFunc("/login", login).Methods("POST")
+
+	v1.HandleFunc("/shopping-list", generateShoppingList).Methods("POST")
+	v1.HandleFunc("/recommendations", getRecommendations).Methods("GET")
+	v1.HandleFunc("/subscribe", authMiddleware(subscribe)).Methods("POST")
+	v1.HandleFunc("/enroll", authMiddleware(enrollOrWaitlist)).Methods("POST")
+	v1.HandleFunc("/enrollments/{id}", authMiddleware(cancelEnrollment)).Methods("DELETE")
+	v1.HandleFunc("/user-history", getUserHistory).Methods("GET")
+	v1.HandleFunc("/user-subscriptions", getUserSubscriptions).Methods("GET")
+	v1.HandleFunc("/stats", getStats).Methods("GET")
+	v1.HandleFunc("/search", searchRecipesIndexed).Methods("GET")
+	v1.HandleFunc("/stream", authMiddleware(streamEvents)).Methods("GET")
+	v1.HandleFunc("/cook/ws", authMiddleware(handleCookingSession)).Methods("GET")
+
+	r.HandleFunc("/", homePage).Methods("GET")
+	r.PathPrefix("/static/").Handler(http.StripPrefix("/static/", http.FileServer(http.Dir("./static/"))))
+
+	// Старые /api/* пути без версии оставлены как временные редиректы на
+	// /api/v1/..., чтобы не ломать клиентов, ещё не перешедших на v1.
+	// Удалить в следующем релизе.
+	r.PathPrefix("/api/").Handler(http.HandlerFunc(legacyAPIRedirect)).MatcherFunc(func(req *http.Request, _ *mux.RouteMatch) bool {
+		return !strings.HasPrefix(req.URL.Path, "/api/v1/")
+	})
+
+	return r
+}
@@ -0,0 +1,408 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"math"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Поля рецепта, по которым строится индекс, и их вес в BM25-скоринге.
+// Заголовок важнее описания, а состав - промежуточное значение между ними.
+const (
+	fieldTitle       = "title"
+	fieldIngredients = "ingredients"
+	fieldDescription = "description"
+)
+
+var fieldWeights = map[string]float64{
+	fieldTitle:       3.0,
+	fieldIngredients: 2.0,
+	fieldDescription: 1.0,
+}
+
+// BM25 параметры
+const bm25K1 = 1.2
+const bm25B = 0.75
+
+var russianStopwords = map[string]bool{
+	"и": true, "в": true, "во": true, "не": true, "на": true, "я": true,
+	"с": true, "со": true, "как": true, "а": true, "то": true, "все": true,
+	"она": true, "так": true, "его": true, "но": true, "да": true, "ты": true,
+	"к": true, "у": true, "же": true, "вы": true, "за": true, "бы": true,
+	"по": true, "только": true, "из": true, "для": true, "от": true, "до": true,
+}
+
+var searchTokenPattern = regexp.MustCompile(`[a-zа-яё0-9]+`)
+
+// stem - упрощённый стеммер в духе Snowball-ru: срезает самые частые
+// падежные/числовые окончания, не претендуя на полную морфологию
+var stemSuffixes = []string{
+	"иями", "ями", "ами", "ого", "ему", "ому", "ыми", "ими",
+	"ах", "ях", "ов", "ев", "ий", "ый", "ая", "яя", "ое", "ее",
+	"ы", "и", "а", "я", "о", "е", "у", "ю", "ь",
+}
+
+func stem(word string) string {
+	if len([]rune(word)) <= 3 {
+		return word
+	}
+	for _, suffix := range stemSuffixes {
+		if strings.HasSuffix(word, suffix) && len([]rune(word))-len([]rune(suffix)) >= 3 {
+			return strings.TrimSuffix(word, suffix)
+		}
+	}
+	return word
+}
+
+// searchTokenize приводит к нижнему регистру, разбивает на слова, выкидывает
+// стоп-слова и применяет лёгкий стеммер
+func searchTokenize(text string) []string {
+	raw := searchTokenPattern.FindAllString(strings.ToLower(text), -1)
+	tokens := make([]string, 0, len(raw))
+	for _, t := range raw {
+		if russianStopwords[t] {
+			continue
+		}
+		tokens = append(tokens, stem(t))
+	}
+	return tokens
+}
+
+type posting struct {
+	recipeID int
+	termFreq int
+}
+
+// searchDoc хранит всё, что нужно для скоринга и сниппетов одного рецепта
+type searchDoc struct {
+	recipeID  int
+	fieldLen  map[string]int
+	fieldText map[string]string
+}
+
+// InvertedIndex - поисковый индекс по рецептам: term -> posting list по полю
+type InvertedIndex struct {
+	mu          sync.RWMutex
+	postings    map[string]map[string][]posting // term -> field -> postings
+	docs        map[int]*searchDoc
+	avgFieldLen map[string]float64
+	docCount    int
+}
+
+func newInvertedIndex() *InvertedIndex {
+	return &InvertedIndex{
+		postings:    map[string]map[string][]posting{},
+		docs:        map[int]*searchDoc{},
+		avgFieldLen: map[string]float64{},
+	}
+}
+
+var globalSearchIndex = newInvertedIndex()
+
+// Rebuild перестраивает индекс целиком по текущему содержимому таблицы recipes
+func (idx *InvertedIndex) Rebuild() error {
+	rows, err := db.Query("SELECT id, title, description, ingredients FROM recipes")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	postings := map[string]map[string][]posting{}
+	docs := map[int]*searchDoc{}
+	fieldTotalLen := map[string]int{}
+
+	for rows.Next() {
+		var id int
+		var title, description, ingredientsJSON string
+		if err := rows.Scan(&id, &title, &description, &ingredientsJSON); err != nil {
+			continue
+		}
+
+		var ingredients []string
+		json.Unmarshal([]byte(ingredientsJSON), &ingredients)
+
+		doc := &searchDoc{
+			recipeID: id,
+			fieldLen: map[string]int{},
+			fieldText: map[string]string{
+				fieldTitle:       title,
+				fieldDescription: description,
+				fieldIngredients: strings.Join(ingredients, " "),
+			},
+		}
+
+		for field, text := range doc.fieldText {
+			tokens := searchTokenize(text)
+			doc.fieldLen[field] = len(tokens)
+			fieldTotalLen[field] += len(tokens)
+
+			freq := map[string]int{}
+			for _, t := range tokens {
+				freq[t]++
+			}
+			for term, tf := range freq {
+				if postings[term] == nil {
+					postings[term] = map[string][]posting{}
+				}
+				postings[term][field] = append(postings[term][field], posting{recipeID: id, termFreq: tf})
+			}
+		}
+
+		docs[id] = doc
+	}
+
+	avgFieldLen := map[string]float64{}
+	if len(docs) > 0 {
+		for field, total := range fieldTotalLen {
+			avgFieldLen[field] = float64(total) / float64(len(docs))
+		}
+	}
+
+	idx.mu.Lock()
+	idx.postings = postings
+	idx.docs = docs
+	idx.avgFieldLen = avgFieldLen
+	idx.docCount = len(docs)
+	idx.mu.Unlock()
+
+	return nil
+}
+
+// IndexRecipe добавляет один новый рецепт без перестройки всего индекса -
+// используется после createRecipe для инкрементального обновления. Повторный
+// вызов для уже проиндексированного id не заменяет его прежние posting-и, а
+// добавляет новые рядом с ними
+func (idx *InvertedIndex) IndexRecipe(id int, title, description string, ingredients []string) {
+	doc := &searchDoc{
+		recipeID: id,
+		fieldLen: map[string]int{},
+		fieldText: map[string]string{
+			fieldTitle:       title,
+			fieldDescription: description,
+			fieldIngredients: strings.Join(ingredients, " "),
+		},
+	}
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	for field, text := range doc.fieldText {
+		tokens := searchTokenize(text)
+		doc.fieldLen[field] = len(tokens)
+
+		freq := map[string]int{}
+		for _, t := range tokens {
+			freq[t]++
+		}
+		for term, tf := range freq {
+			if idx.postings[term] == nil {
+				idx.postings[term] = map[string][]posting{}
+			}
+			idx.postings[term][field] = append(idx.postings[term][field], posting{recipeID: id, termFreq: tf})
+		}
+	}
+
+	idx.docs[id] = doc
+	idx.docCount++
+}
+
+func (idx *InvertedIndex) docFreq(term, field string) int {
+	return len(idx.postings[term][field])
+}
+
+func (idx *InvertedIndex) idf(term, field string) float64 {
+	df := idx.docFreq(term, field)
+	if df == 0 {
+		return 0
+	}
+	n := float64(idx.docCount)
+	return math.Log(1 + (n-float64(df)+0.5)/(float64(df)+0.5))
+}
+
+type searchHit struct {
+	RecipeID int     `json:"recipe_id"`
+	Score    float64 `json:"score"`
+}
+
+// Search вычисляет BM25-скор запроса по всем полям с их весами и возвращает
+// отсортированные по убыванию релевантности совпадения
+func (idx *InvertedIndex) Search(query string) []searchHit {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	terms := searchTokenize(query)
+	scores := map[int]float64{}
+
+	for _, term := range terms {
+		for field, weight := range fieldWeights {
+			postingsList := idx.postings[term][field]
+			if len(postingsList) == 0 {
+				continue
+			}
+			idf := idx.idf(term, field)
+			avgLen := idx.avgFieldLen[field]
+			if avgLen == 0 {
+				avgLen = 1
+			}
+
+			for _, p := range postingsList {
+				doc := idx.docs[p.recipeID]
+				if doc == nil {
+					continue
+				}
+				fieldLen := float64(doc.fieldLen[field])
+				tf := float64(p.termFreq)
+
+				numerator := tf * (bm25K1 + 1)
+				denominator := tf + bm25K1*(1-bm25B+bm25B*fieldLen/avgLen)
+				scores[p.recipeID] += weight * idf * (numerator / denominator)
+			}
+		}
+	}
+
+	hits := make([]searchHit, 0, len(scores))
+	for id, score := range scores {
+		hits = append(hits, searchHit{RecipeID: id, Score: score})
+	}
+	sort.Slice(hits, func(i, j int) bool { return hits[i].Score > hits[j].Score })
+	return hits
+}
+
+const snippetRadius = 6
+
+// snippet возвращает фрагмент текста вокруг первого вхождения одного из
+// терминов запроса, с выделением совпадения **жирным** markdown-style
+func snippet(text string, queryTerms []string) string {
+	words := strings.Fields(text)
+	lowerWords := make([]string, len(words))
+	for i, w := range words {
+		lowerWords[i] = strings.ToLower(w)
+	}
+
+	matchAt := -1
+	for i, w := range lowerWords {
+		stemmed := stem(w)
+		for _, term := range queryTerms {
+			if strings.Contains(w, term) || stemmed == term {
+				matchAt = i
+				break
+			}
+		}
+		if matchAt >= 0 {
+			break
+		}
+	}
+
+	if matchAt == -1 {
+		if len(words) > 2*snippetRadius {
+			return strings.Join(words[:2*snippetRadius], " ") + "..."
+		}
+		return text
+	}
+
+	start := matchAt - snippetRadius
+	if start < 0 {
+		start = 0
+	}
+	end := matchAt + snippetRadius + 1
+	if end > len(words) {
+		end = len(words)
+	}
+
+	fragment := append([]string{}, words[start:matchAt]...)
+	fragment = append(fragment, "**"+words[matchAt]+"**")
+	fragment = append(fragment, words[matchAt+1:end]...)
+
+	result := strings.Join(fragment, " ")
+	if start > 0 {
+		result = "..." + result
+	}
+	if end < len(words) {
+		result += "..."
+	}
+	return result
+}
+
+type searchResult struct {
+	Recipe  Recipe  `json:"recipe"`
+	Score   float64 `json:"score"`
+	Snippet string  `json:"snippet"`
+}
+
+// searchRecipesIndexed заменяет поиск по LIKE на полнотекстовый поиск с BM25 и
+// поддержкой фасетов: ?q=..&cuisine=..&exclude=..
+// max_time и difficulty из запроса пока не поддерживаются схемой рецептов.
+func searchRecipesIndexed(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query().Get("q")
+	if query == "" {
+		http.Error(w, "search query is required", http.StatusBadRequest)
+		return
+	}
+
+	cuisine := r.URL.Query().Get("cuisine")
+	exclude := strings.ToLower(r.URL.Query().Get("exclude"))
+
+	hits := globalSearchIndex.Search(query)
+	queryTerms := searchTokenize(query)
+
+	results := make([]searchResult, 0, len(hits))
+	for _, hit := range hits {
+		recipe, ok := loadRecipeByID(hit.RecipeID)
+		if !ok {
+			continue
+		}
+
+		if cuisine != "" {
+			var speciality string
+			db.QueryRow("SELECT speciality FROM chefs WHERE id = ?", recipe.ChefID).Scan(&speciality)
+			if !strings.Contains(strings.ToLower(speciality), strings.ToLower(cuisine)) {
+				continue
+			}
+		}
+
+		if exclude != "" {
+			excluded := false
+			for _, ingredient := range recipe.Ingredients {
+				if strings.Contains(strings.ToLower(ingredient), exclude) {
+					excluded = true
+					break
+				}
+			}
+			if excluded {
+				continue
+			}
+		}
+
+		text := recipe.Title + " " + recipe.Description + " " + strings.Join(recipe.Ingredients, " ")
+		results = append(results, searchResult{
+			Recipe:  recipe,
+			Score:   hit.Score,
+			Snippet: snippet(text, queryTerms),
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(results)
+}
+
+const searchRebuildInterval = 5 * time.Minute
+
+// startSearchIndexRebuilder периодически перестраивает индекс в фоне, чтобы
+// сгладить любое расхождение, накопившееся за счёт инкрементальных обновлений
+func startSearchIndexRebuilder() {
+	go func() {
+		ticker := time.NewTicker(searchRebuildInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if err := globalSearchIndex.Rebuild(); err != nil {
+				log.Printf("Error rebuilding search index: %v", err)
+			}
+		}
+	}()
+}
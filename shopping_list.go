@@ -0,0 +1,313 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Мастер-класс не хранит собственный список ингредиентов - он учится у
+// рецептов своего повара (та же связь mc.chef_id -> recipes.chef_id, что и в
+// recommender.go RecommendForUser), поэтому список покупок собирается по
+// рецептам всех поваров выбранных мастер-классов.
+type shoppingListItem struct {
+	MasterClassID int `json:"master_class_id"`
+	Servings      int `json:"servings"`
+}
+
+type shoppingListRequest struct {
+	Items        []shoppingListItem `json:"items"`
+	ShoppingDate string             `json:"shopping_date,omitempty"`
+}
+
+// unitConversion описывает категорию единицы измерения (чтобы суммировать
+// только сопоставимые величины) и коэффициент приведения к базовой единице
+// категории: граммы для массы, миллилитры для объёма, штуки для счёта
+type unitConversion struct {
+	category string
+	factor   float64
+}
+
+var unitConversions = map[string]unitConversion{
+	"г":     {"mass", 1},
+	"гр":    {"mass", 1},
+	"кг":    {"mass", 1000},
+	"мл":    {"volume", 1},
+	"л":     {"volume", 1000},
+	"ст.л.": {"volume", 15},
+	"ч.л.":  {"volume", 5},
+	"шт":    {"count", 1},
+	"штуки": {"count", 1},
+	"pcs":   {"count", 1},
+}
+
+var ingredientQuantityPattern = regexp.MustCompile(`(?i)^\s*(\d+(?:[.,]\d+)?)\s*(кг|гр|г|л|мл|ст\.л\.|ч\.л\.|штуки|шт|pcs)?\.?\s+(.+)$`)
+
+// substitutions - небольшая статическая карта замен для самых частых
+// ингредиентов, на которые спрашивают альтернативу (аллергии, веганство и т.п.)
+var substitutions = map[string][]string{
+	"масло":           {"маргарин"},
+	"сливочное масло": {"маргарин", "кокосовое масло"},
+	"молоко":          {"соевое молоко", "миндальное молоко"},
+	"сметана":         {"йогурт"},
+	"яйца":            {"банановое пюре", "льняной гель"},
+	"сахар":           {"мёд", "кленовый сироп"},
+	"пармезан":        {"пищевые дрожжи"},
+}
+
+// parseIngredient разбирает строку ингредиента вида "200 г мука" на
+// количество, единицу измерения и название. Если количество не указано
+// (большинство текущих рецептов хранят голые названия), считает ингредиент
+// одной штукой
+func parseIngredient(raw string) (quantity float64, unit string, name string) {
+	match := ingredientQuantityPattern.FindStringSubmatch(raw)
+	if match == nil {
+		return 1, "шт", strings.TrimSpace(raw)
+	}
+
+	qty, err := strconv.ParseFloat(strings.Replace(match[1], ",", ".", 1), 64)
+	if err != nil {
+		return 1, "шт", strings.TrimSpace(raw)
+	}
+
+	unit = strings.ToLower(match[2])
+	if unit == "" {
+		unit = "шт"
+	}
+	return qty, unit, strings.TrimSpace(match[3])
+}
+
+type aggregatedIngredient struct {
+	Name          string   `json:"name"`
+	Quantity      float64  `json:"quantity"`
+	Unit          string   `json:"unit"`
+	Substitutions []string `json:"substitutions,omitempty"`
+}
+
+// recipeContribution - ингредиенты одного рецепта одного из выбранных
+// мастер-классов вместе с числом порций. Это список, а не map по ID, потому
+// что один и тот же мастер-класс (или рецепт, общий для нескольких
+// мастер-классов одного повара) может встретиться несколько раз - тогда
+// вклады должны суммироваться, а не перезаписывать друг друга
+type recipeContribution struct {
+	ingredients []string
+	servings    int
+}
+
+// aggregateIngredients суммирует количества одноимённых ингредиентов по всем
+// вкладам, приводя их к базовой единице своей категории (масса/объём/счёт), и
+// переводит итог в более читаемую единицу (кг вместо 1000 г и т.п.)
+func aggregateIngredients(contributions []recipeContribution) []aggregatedIngredient {
+	type totals struct {
+		displayName string
+		category    string
+		amount      float64
+	}
+	sums := map[string]*totals{}
+
+	for _, contribution := range contributions {
+		servings := contribution.servings
+		if servings <= 0 {
+			servings = 1
+		}
+
+		for _, raw := range contribution.ingredients {
+			qty, unit, name := parseIngredient(raw)
+			conv, ok := unitConversions[unit]
+			if !ok {
+				conv = unitConversion{category: "count", factor: 1}
+			}
+
+			key := strings.ToLower(name) + "|" + conv.category
+			if sums[key] == nil {
+				sums[key] = &totals{displayName: name, category: conv.category}
+			}
+			sums[key].amount += qty * conv.factor * float64(servings)
+		}
+	}
+
+	result := make([]aggregatedIngredient, 0, len(sums))
+	for _, total := range sums {
+		quantity, unit := displayUnit(total.category, total.amount)
+		result = append(result, aggregatedIngredient{
+			Name:          total.displayName,
+			Quantity:      quantity,
+			Unit:          unit,
+			Substitutions: substitutions[strings.ToLower(total.displayName)],
+		})
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
+// displayUnit переводит сумму в базовой единице обратно в удобную для
+// человека единицу: килограммы/литры при крупных значениях, иначе граммы/мл
+func displayUnit(category string, amount float64) (float64, string) {
+	switch category {
+	case "mass":
+		if amount >= 1000 {
+			return amount / 1000, "кг"
+		}
+		return amount, "г"
+	case "volume":
+		if amount >= 1000 {
+			return amount / 1000, "л"
+		}
+		return amount, "мл"
+	default:
+		return amount, "шт"
+	}
+}
+
+// shoppingListFormat определяет формат ответа: ?format= имеет приоритет над
+// заголовком Accept, по умолчанию - json
+func shoppingListFormat(r *http.Request) string {
+	if format := r.URL.Query().Get("format"); format != "" {
+		return format
+	}
+
+	accept := r.Header.Get("Accept")
+	switch {
+	case strings.Contains(accept, "text/csv"):
+		return "csv"
+	case strings.Contains(accept, "text/calendar"):
+		return "ics"
+	case strings.Contains(accept, "text/markdown"):
+		return "markdown"
+	case strings.Contains(accept, "text/plain"):
+		return "text"
+	default:
+		return "json"
+	}
+}
+
+func writeShoppingListText(w http.ResponseWriter, items []aggregatedIngredient) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	var sb strings.Builder
+	for _, item := range items {
+		fmt.Fprintf(&sb, "%s: %.2f %s\n", item.Name, item.Quantity, item.Unit)
+	}
+	w.Write([]byte(sb.String()))
+}
+
+func writeShoppingListMarkdown(w http.ResponseWriter, items []aggregatedIngredient) {
+	w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+	var sb strings.Builder
+	sb.WriteString("# Список покупок\n\n")
+	for _, item := range items {
+		fmt.Fprintf(&sb, "- [ ] %s - %.2f %s", item.Name, item.Quantity, item.Unit)
+		if len(item.Substitutions) > 0 {
+			fmt.Fprintf(&sb, " (замена: %s)", strings.Join(item.Substitutions, ", "))
+		}
+		sb.WriteString("\n")
+	}
+	w.Write([]byte(sb.String()))
+}
+
+func writeShoppingListCSV(w http.ResponseWriter, items []aggregatedIngredient) {
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"name", "quantity", "unit", "substitutions"})
+	for _, item := range items {
+		writer.Write([]string{
+			item.Name,
+			strconv.FormatFloat(item.Quantity, 'f', 2, 64),
+			item.Unit,
+			strings.Join(item.Substitutions, "; "),
+		})
+	}
+	writer.Flush()
+}
+
+// writeShoppingListICS отдаёт напоминание о походе за покупками как событие
+// календаря на выбранную дату (или без даты, если она не указана)
+func writeShoppingListICS(w http.ResponseWriter, items []aggregatedIngredient, shoppingDate string) {
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+
+	var description strings.Builder
+	for _, item := range items {
+		fmt.Fprintf(&description, "%s %.2f %s\\n", item.Name, item.Quantity, item.Unit)
+	}
+
+	fmt.Fprintf(w, "BEGIN:VCALENDAR\r\n")
+	fmt.Fprintf(w, "VERSION:2.0\r\n")
+	fmt.Fprintf(w, "PRODID:-//web_seminar//shopping-list//RU\r\n")
+	fmt.Fprintf(w, "BEGIN:VEVENT\r\n")
+	fmt.Fprintf(w, "SUMMARY:Поход за покупками\r\n")
+	if shoppingDate != "" {
+		fmt.Fprintf(w, "DTSTART;VALUE=DATE:%s\r\n", strings.ReplaceAll(shoppingDate, "-", ""))
+	}
+	fmt.Fprintf(w, "DESCRIPTION:%s\r\n", description.String())
+	fmt.Fprintf(w, "BEGIN:VALARM\r\n")
+	fmt.Fprintf(w, "ACTION:DISPLAY\r\n")
+	fmt.Fprintf(w, "DESCRIPTION:Не забудьте купить продукты\r\n")
+	fmt.Fprintf(w, "TRIGGER:-PT1H\r\n")
+	fmt.Fprintf(w, "END:VALARM\r\n")
+	fmt.Fprintf(w, "END:VEVENT\r\n")
+	fmt.Fprintf(w, "END:VCALENDAR\r\n")
+}
+
+// generateShoppingList принимает список мастер-классов с числом порций,
+// собирает ингредиенты рецептов поваров этих мастер-классов, суммирует и
+// нормализует их и отдаёт результат в формате, выбранном через ?format= или
+// заголовок Accept: json, text, markdown, csv, ics
+func generateShoppingList(w http.ResponseWriter, r *http.Request) {
+	var req shoppingListRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.Items) == 0 {
+		http.Error(w, "at least one item with master_class_id is required", http.StatusBadRequest)
+		return
+	}
+
+	var contributions []recipeContribution
+
+	for _, item := range req.Items {
+		var chefID int
+		if err := db.QueryRow("SELECT chef_id FROM master_classes WHERE id = ?", item.MasterClassID).Scan(&chefID); err != nil {
+			continue
+		}
+
+		rows, err := db.Query("SELECT ingredients FROM recipes WHERE chef_id = ?", chefID)
+		if err != nil {
+			continue
+		}
+
+		for rows.Next() {
+			var ingredientsJSON string
+			if err := rows.Scan(&ingredientsJSON); err != nil {
+				continue
+			}
+
+			var ingredients []string
+			json.Unmarshal([]byte(ingredientsJSON), &ingredients)
+			contributions = append(contributions, recipeContribution{ingredients: ingredients, servings: item.Servings})
+		}
+		rows.Close()
+	}
+
+	if len(contributions) == 0 {
+		http.Error(w, "none of the requested master classes were found", http.StatusNotFound)
+		return
+	}
+
+	aggregated := aggregateIngredients(contributions)
+
+	switch shoppingListFormat(r) {
+	case "text":
+		writeShoppingListText(w, aggregated)
+	case "markdown":
+		writeShoppingListMarkdown(w, aggregated)
+	case "csv":
+		writeShoppingListCSV(w, aggregated)
+	case "ics":
+		writeShoppingListICS(w, aggregated, req.ShoppingDate)
+	default:
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"shopping_list": aggregated})
+	}
+}
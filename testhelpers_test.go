@@ -0,0 +1,33 @@
+package main
+
+import (
+	"database/sql"
+	"testing"
+)
+
+// setupTestDB переключает глобальную db на свежую in-memory SQLite базу со
+// всеми таблицами и восстанавливает прежнюю db по завершении теста
+func setupTestDB(t *testing.T) {
+	t.Helper()
+
+	testDB, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("Error opening test database: %v", err)
+	}
+	// Одно соединение, иначе каждое новое подключение пула получит свою
+	// отдельную :memory: базу и увидит пустые таблицы
+	testDB.SetMaxOpenConns(1)
+
+	previous := db
+	db = testDB
+	createTables()
+	createSessionsTable()
+	addAuthColumns()
+	createWaitlistTable()
+	addTranscriptColumn()
+
+	t.Cleanup(func() {
+		testDB.Close()
+		db = previous
+	})
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+)
+
+func createWaitlistTable() {
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS waitlist (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL,
+		master_class_id INTEGER NOT NULL,
+		position INTEGER NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE(user_id, master_class_id),
+		FOREIGN KEY (user_id) REFERENCES users (id),
+		FOREIGN KEY (master_class_id) REFERENCES master_classes (id)
+	)`)
+	if err != nil {
+		log.Printf("Error creating waitlist table: %v", err)
+	}
+}
+
+// enrollOrWaitlist записывает пользователя на мастер-класс, если есть места,
+// иначе ставит его в конец очереди ожидания. Проверки мест и дублей выполняются
+// внутри одной транзакции, чтобы избежать гонок при параллельных заявках.
+func enrollOrWaitlist(w http.ResponseWriter, r *http.Request) {
+	user, ok := userFromContext(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var enrollment Enrollment
+	if err := json.NewDecoder(r.Body).Decode(&enrollment); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	enrollment.UserID = user.ID
+
+	tx, err := db.Begin()
+	if err != nil {
+		log.Printf("Error starting enrollment transaction: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	var alreadyEnrolled, alreadyWaitlisted int
+	tx.QueryRow("SELECT COUNT(*) FROM user_history WHERE user_id = ? AND master_class_id = ?",
+		enrollment.UserID, enrollment.MasterClassID).Scan(&alreadyEnrolled)
+	tx.QueryRow("SELECT COUNT(*) FROM waitlist WHERE user_id = ? AND master_class_id = ?",
+		enrollment.UserID, enrollment.MasterClassID).Scan(&alreadyWaitlisted)
+
+	if alreadyEnrolled > 0 {
+		http.Error(w, "Already enrolled", http.StatusConflict)
+		return
+	}
+	if alreadyWaitlisted > 0 {
+		http.Error(w, "Already on the waitlist", http.StatusConflict)
+		return
+	}
+
+	var currentEnrollments, maxStudents int
+	err = tx.QueryRow(`
+		SELECT COUNT(uh.id), mc.max_students
+		FROM master_classes mc
+		LEFT JOIN user_history uh ON mc.id = uh.master_class_id
+		WHERE mc.id = ?
+		GROUP BY mc.id, mc.max_students
+	`, enrollment.MasterClassID).Scan(&currentEnrollments, &maxStudents)
+
+	if err == sql.ErrNoRows {
+		http.Error(w, "Master class not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Error checking enrollment capacity: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if currentEnrollments < maxStudents {
+		if _, err := tx.Exec("INSERT INTO user_history (user_id, master_class_id) VALUES (?, ?)",
+			enrollment.UserID, enrollment.MasterClassID); err != nil {
+			log.Printf("Error enrolling user: %v", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		if err := tx.Commit(); err != nil {
+			log.Printf("Error committing enrollment: %v", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"status": "enrolled"})
+		return
+	}
+
+	var nextPosition int
+	tx.QueryRow("SELECT COALESCE(MAX(position), 0) + 1 FROM waitlist WHERE master_class_id = ?",
+		enrollment.MasterClassID).Scan(&nextPosition)
+
+	if _, err := tx.Exec("INSERT INTO waitlist (user_id, master_class_id, position) VALUES (?, ?, ?)",
+		enrollment.UserID, enrollment.MasterClassID, nextPosition); err != nil {
+		log.Printf("Error adding to waitlist: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("Error committing waitlist entry: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":   "waitlisted",
+		"position": nextPosition,
+	})
+}
+
+// cancelEnrollment удаляет запись пользователя на мастер-класс и, в той же
+// транзакции, продвигает первого из очереди ожидания в user_history
+func cancelEnrollment(w http.ResponseWriter, r *http.Request) {
+	user, ok := userFromContext(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	masterClassID, err := idFromPath(r)
+	if err != nil {
+		http.Error(w, "Invalid enrollment id", http.StatusBadRequest)
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		log.Printf("Error starting cancellation transaction: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec("DELETE FROM user_history WHERE user_id = ? AND master_class_id = ?", user.ID, masterClassID)
+	if err != nil {
+		log.Printf("Error cancelling enrollment: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		http.Error(w, "Enrollment not found", http.StatusNotFound)
+		return
+	}
+
+	var promotedUserID int
+	var waitlistID int
+	err = tx.QueryRow("SELECT id, user_id FROM waitlist WHERE master_class_id = ? ORDER BY position ASC LIMIT 1",
+		masterClassID).Scan(&waitlistID, &promotedUserID)
+
+	promoted := false
+	if err == nil {
+		if _, err := tx.Exec("INSERT INTO user_history (user_id, master_class_id) VALUES (?, ?)", promotedUserID, masterClassID); err != nil {
+			log.Printf("Error promoting waitlisted user: %v", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if _, err := tx.Exec("DELETE FROM waitlist WHERE id = ?", waitlistID); err != nil {
+			log.Printf("Error removing promoted user from waitlist: %v", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		promoted = true
+	} else if err != sql.ErrNoRows {
+		log.Printf("Error checking waitlist: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("Error committing cancellation: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if promoted {
+		publishEvent(promotedUserID, Event{Type: "waitlist_promoted", MasterClassID: masterClassID})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]bool{"cancelled": true, "promoted": promoted})
+}
+
+type rosterResponse struct {
+	MasterClassID int `json:"master_class_id"`
+	Enrolled      int `json:"enrolled"`
+	Waitlisted    int `json:"waitlisted"`
+	MaxStudents   int `json:"max_students"`
+}
+
+func getMasterClassRoster(w http.ResponseWriter, r *http.Request) {
+	masterClassID, err := idFromPath(r)
+	if err != nil {
+		http.Error(w, "Invalid master class id", http.StatusBadRequest)
+		return
+	}
+
+	var roster rosterResponse
+	roster.MasterClassID = masterClassID
+
+	err = db.QueryRow("SELECT max_students FROM master_classes WHERE id = ?", masterClassID).Scan(&roster.MaxStudents)
+	if err != nil {
+		http.Error(w, "Master class not found", http.StatusNotFound)
+		return
+	}
+
+	db.QueryRow("SELECT COUNT(*) FROM user_history WHERE master_class_id = ?", masterClassID).Scan(&roster.Enrolled)
+	db.QueryRow("SELECT COUNT(*) FROM waitlist WHERE master_class_id = ?", masterClassID).Scan(&roster.Waitlisted)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(roster)
+}
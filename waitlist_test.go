@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func withAuthUser(r *http.Request, user *AuthUser) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), ctxUserKey, user))
+}
+
+func mustInsertUser(t *testing.T, username string) int {
+	t.Helper()
+	res, err := db.Exec("INSERT INTO users (username, email, preferences) VALUES (?, ?, ?)", username, username+"@example.com", "")
+	if err != nil {
+		t.Fatalf("Error inserting test user: %v", err)
+	}
+	id, _ := res.LastInsertId()
+	return int(id)
+}
+
+func mustInsertMasterClass(t *testing.T, maxStudents int) int {
+	t.Helper()
+	res, err := db.Exec("INSERT INTO master_classes (title, chef_id, max_students) VALUES (?, ?, ?)", "Test Class", 1, maxStudents)
+	if err != nil {
+		t.Fatalf("Error inserting test master class: %v", err)
+	}
+	id, _ := res.LastInsertId()
+	return int(id)
+}
+
+func TestEnrollOrWaitlist_MasterClassNotFound(t *testing.T) {
+	setupTestDB(t)
+	userID := mustInsertUser(t, "no_class_user")
+
+	body, _ := json.Marshal(Enrollment{MasterClassID: 999})
+	r := httptest.NewRequest("POST", "/api/v1/enroll", bytes.NewReader(body))
+	r = withAuthUser(r, &AuthUser{ID: userID, Role: RoleUser})
+	w := httptest.NewRecorder()
+
+	enrollOrWaitlist(w, r)
+
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for nonexistent master class, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestEnrollOrWaitlist_CapacityAndWaitlist(t *testing.T) {
+	setupTestDB(t)
+	masterClassID := mustInsertMasterClass(t, 1)
+	firstUserID := mustInsertUser(t, "early_bird")
+	secondUserID := mustInsertUser(t, "latecomer")
+
+	enroll := func(userID int) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(Enrollment{MasterClassID: masterClassID})
+		r := httptest.NewRequest("POST", "/api/v1/enroll", bytes.NewReader(body))
+		r = withAuthUser(r, &AuthUser{ID: userID, Role: RoleUser})
+		w := httptest.NewRecorder()
+		enrollOrWaitlist(w, r)
+		return w
+	}
+
+	firstResp := enroll(firstUserID)
+	if firstResp.Code != http.StatusOK {
+		t.Fatalf("expected first enrollment to succeed, got %d: %s", firstResp.Code, firstResp.Body.String())
+	}
+	var firstBody map[string]string
+	json.Unmarshal(firstResp.Body.Bytes(), &firstBody)
+	if firstBody["status"] != "enrolled" {
+		t.Fatalf("expected status=enrolled, got %q", firstBody["status"])
+	}
+
+	secondResp := enroll(secondUserID)
+	if secondResp.Code != http.StatusOK {
+		t.Fatalf("expected second enrollment to succeed (waitlisted), got %d: %s", secondResp.Code, secondResp.Body.String())
+	}
+	var secondBody map[string]interface{}
+	json.Unmarshal(secondResp.Body.Bytes(), &secondBody)
+	if secondBody["status"] != "waitlisted" {
+		t.Fatalf("expected status=waitlisted once capacity is full, got %q", secondBody["status"])
+	}
+}
+
+func TestCancelEnrollment_PromotesWaitlist(t *testing.T) {
+	setupTestDB(t)
+	masterClassID := mustInsertMasterClass(t, 1)
+	firstUserID := mustInsertUser(t, "early_bird2")
+	secondUserID := mustInsertUser(t, "latecomer2")
+
+	if _, err := db.Exec("INSERT INTO user_history (user_id, master_class_id) VALUES (?, ?)", firstUserID, masterClassID); err != nil {
+		t.Fatalf("Error seeding enrollment: %v", err)
+	}
+	if _, err := db.Exec("INSERT INTO waitlist (user_id, master_class_id, position) VALUES (?, ?, 1)", secondUserID, masterClassID); err != nil {
+		t.Fatalf("Error seeding waitlist: %v", err)
+	}
+
+	r := httptest.NewRequest("DELETE", "/api/v1/enrollments/"+strconv.Itoa(masterClassID), nil)
+	r = withAuthUser(r, &AuthUser{ID: firstUserID, Role: RoleUser})
+	r = mux.SetURLVars(r, map[string]string{"id": strconv.Itoa(masterClassID)})
+	w := httptest.NewRecorder()
+
+	cancelEnrollment(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var resp map[string]bool
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if !resp["cancelled"] || !resp["promoted"] {
+		t.Fatalf("expected cancelled and promoted to both be true, got %+v", resp)
+	}
+
+	var promotedCount int
+	db.QueryRow("SELECT COUNT(*) FROM user_history WHERE user_id = ? AND master_class_id = ?", secondUserID, masterClassID).Scan(&promotedCount)
+	if promotedCount != 1 {
+		t.Fatal("expected promoted user to appear in user_history")
+	}
+
+	var remainingWaitlist int
+	db.QueryRow("SELECT COUNT(*) FROM waitlist WHERE master_class_id = ?", masterClassID).Scan(&remainingWaitlist)
+	if remainingWaitlist != 0 {
+		t.Fatal("expected promoted user to be removed from the waitlist")
+	}
+}